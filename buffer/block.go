@@ -0,0 +1,241 @@
+package buffer
+
+import (
+	"strings"
+
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+// A block selection is the rectangle of screen columns between BlockX,
+// BlockY and the cursor. CurX is not kept to the end of short lines while
+// the block is being drawn, so it can reach past them. A rune is inside the
+// block if the column it starts at is, so tabs are in or out whole.
+
+// blockCursors returns a cursor for each line of the block, from the top,
+// selecting the part of that line inside it.
+func (b *Buffer) blockCursors() []Cursor {
+	y0, y1 := b.BlockY, b.CurY
+	if y0 > y1 {
+		y0, y1 = y1, y0
+	}
+	cursors := make([]Cursor, 0, y1-y0+1)
+	for y := y0; y <= y1; y++ {
+		cursors = append(cursors, Cursor{Pos: b.GetPos(b.CurX, y), Sel: b.GetPos(b.BlockX, y)})
+	}
+	return cursors
+}
+
+// extraCursors returns the cursors besides the primary one: those on the
+// other lines of a block selection, or Cursors.
+func (b *Buffer) extraCursors() []Cursor {
+	if !b.Block {
+		return b.Cursors
+	}
+	cursors := b.blockCursors()
+	i := b.CurY - b.BlockY
+	if i < 0 {
+		i = 0
+	}
+	return append(cursors[:i], cursors[i+1:]...)
+}
+
+// BlockText returns the part of each line inside the block selection.
+func (b *Buffer) BlockText() []string {
+	var rows []string
+	for _, c := range b.blockCursors() {
+		start, end := c.Sel, c.Pos
+		if start > end {
+			start, end = end, start
+		}
+		rows = append(rows, string(b.runes(start, end)))
+	}
+	return rows
+}
+
+func (b *Buffer) runes(start, end int) []rune {
+	text := make([]rune, 0, end-start)
+	for l1 := start; l1 < end; l1++ {
+		text = append(text, b.GB.Get(l1))
+	}
+	return text
+}
+
+// blockToCursors turns the block selection into a cursor on each of its
+// lines, selecting the part inside it, so typing goes into every row.
+func (b *Buffer) blockToCursors() {
+	i := b.CurY - b.BlockY
+	if i < 0 {
+		i = 0
+	}
+	cursors := b.blockCursors()
+	primary := cursors[i]
+	b.Block = false
+	b.Cursors = append(cursors[:i], cursors[i+1:]...)
+	b.SetPos(primary.Pos)
+	b.Sel = primary.Sel
+	b.mergeCursors()
+}
+
+// DeleteBlock removes the text inside the block selection, leaving an
+// empty block at its left edge.
+func (b *Buffer) DeleteBlock() {
+	b.BeginEdit()
+	defer b.EndEdit()
+	cursors := b.blockCursors()
+	for l1 := len(cursors) - 1; l1 >= 0; l1-- {
+		start, end := cursors[l1].Sel, cursors[l1].Pos
+		if start > end {
+			start, end = end, start
+		}
+		b.Remove(start, end-start)
+	}
+	if b.CurX > b.BlockX {
+		b.CurX = b.BlockX
+	}
+	b.BlockX = b.CurX
+}
+
+// PasteBlock inserts rows one under another, each at column x of its line
+// starting from line y, padding short lines with spaces and adding lines
+// at the end of the buffer as needed. The cursor is left after the last
+// row.
+func (b *Buffer) PasteBlock(x, y int, rows []string) {
+	b.BeginEdit()
+	defer b.EndEdit()
+	for i, row := range rows {
+		line := y + i
+		if line > b.Height() {
+			b.InsertAt(b.GB.Len(), []rune{'\n'})
+		}
+		pos := b.GetPos(x, line)
+		if col, _ := b.GetCur(pos); col < x {
+			b.InsertAt(pos, []rune(strings.Repeat(" ", x-col)))
+			pos += x - col
+		}
+		b.InsertAt(pos, []rune(row))
+		b.SetPos(pos + len([]rune(row)))
+	}
+}
+
+// isBlockCut reports whether the cut buffer holds a block copied by
+// blockKey, rather than ordinary text.
+func (b *Buffer) isBlockCut() bool {
+	return b.cutBlock != nil && strings.Join(b.cutBlock, "\n") == string(b.CutBuf)
+}
+
+// startBlock anchors a block selection at the cursor, if there isn't one
+// already.
+func (b *Buffer) startBlock() {
+	if b.Block {
+		return
+	}
+	b.Sel = -1
+	b.Cursors = nil
+	b.CurX, b.CurY = b.GetCur(b.Pos())
+	b.Block = true
+	b.BlockX, b.BlockY = b.CurX, b.CurY
+}
+
+// blockKey handles Alt+Shift with the arrow keys, which draw a block
+// selection, and the keys that act on one. Keys that edit the text turn
+// the block into a cursor on each of its lines, then leave it to the
+// usual handling; those that move the cursor end the block selection.
+func (b *Buffer) blockKey(evt termbox.Event) bool {
+	if evt.Mod&(termbox.ModAlt|termbox.ModShift) == termbox.ModAlt|termbox.ModShift {
+		switch evt.Key {
+		case termbox.KeyArrowLeft:
+			b.startBlock()
+			if b.CurX > 0 {
+				b.CurX--
+			}
+			return true
+		case termbox.KeyArrowRight:
+			b.startBlock()
+			b.CurX++
+			return true
+		case termbox.KeyArrowUp:
+			b.startBlock()
+			if b.CurY > 0 {
+				b.CurY--
+			}
+			return true
+		case termbox.KeyArrowDown:
+			b.startBlock()
+			if b.CurY < b.Height() {
+				b.CurY++
+			}
+			return true
+		}
+	}
+
+	if !b.Block {
+		if (evt.Key == termbox.KeyCtrlV || evt.Key == termbox.KeyCtrlU) && len(b.Cursors) == 0 && b.isBlockCut() {
+			b.BeginEdit()
+			defer b.EndEdit()
+			b.DeleteSelection()
+			x, y := b.GetCur(b.Pos())
+			b.PasteBlock(x, y, b.cutBlock)
+			return true
+		}
+		return false
+	}
+
+	switch evt.Key {
+	case termbox.KeyEsc:
+		b.Block = false
+		return true
+	case termbox.KeyCtrlC:
+		b.cutBlock = b.BlockText()
+		b.CutBuf = []rune(strings.Join(b.cutBlock, "\n"))
+		b.LastCut = -1
+		return true
+	case termbox.KeyCtrlK:
+		b.cutBlock = b.BlockText()
+		b.CutBuf = []rune(strings.Join(b.cutBlock, "\n"))
+		b.LastCut = -1
+		b.DeleteBlock()
+		return true
+	case termbox.KeyCtrlV, termbox.KeyCtrlU:
+		if !b.isBlockCut() {
+			break
+		}
+		b.BeginEdit()
+		defer b.EndEdit()
+		b.DeleteBlock()
+		y := b.BlockY
+		if b.CurY < y {
+			y = b.CurY
+		}
+		b.PasteBlock(b.BlockX, y, b.cutBlock)
+		b.Block = false
+		return true
+	case termbox.KeyArrowLeft, termbox.KeyArrowRight, termbox.KeyArrowUp, termbox.KeyArrowDown,
+		termbox.KeyHome, termbox.KeyEnd, termbox.KeyPgup, termbox.KeyPgdn:
+		b.Block = false
+		return false
+	}
+	b.blockToCursors()
+	return false
+}
+
+// blockMouse handles Alt with the left button: a click anchors a block
+// selection and dragging draws it out.
+func (b *Buffer) blockMouse(r core.Rect, evt termbox.Event) {
+	pos := b.PosAt(r, evt.MouseX, evt.MouseY)
+	x, y := b.GetCur(pos)
+	// Past the end of a line, take the column clicked on rather than the
+	// end of the line, so the block can reach beyond short lines.
+	if tr := b.textRect(r); !b.Wrap && pos == b.LineEnd(y) {
+		if c := evt.MouseX - tr.X + b.xScroll(); c > x {
+			x = c
+		}
+	}
+	if evt.Mod&termbox.ModMotion == 0 || !b.Block {
+		b.Sel = -1
+		b.Cursors = nil
+		b.Block = true
+		b.BlockX, b.BlockY = x, y
+	}
+	b.CurX, b.CurY = x, y
+}
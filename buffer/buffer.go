@@ -3,6 +3,8 @@ package buffer
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/andyleap/editor/core"
 	"github.com/andyleap/gapbuffer"
@@ -29,167 +31,202 @@ type Buffer struct {
 	GB *gapbuffer.GapBuffer
 
 	Scroll     int
+	XScroll    int
 	CurX, CurY int
 
 	Sel int
+	// Cursors are any further cursors, besides CurX/CurY and Sel, that
+	// edits are made at too.
+	Cursors []Cursor
+	// Block makes the selection a rectangle, from column BlockX of line
+	// BlockY to the cursor, rather than the text between Sel and the
+	// cursor.
+	Block          bool
+	BlockX, BlockY int
 
 	CutBuf  []rune
 	LastCut int
+	// cutParts is what copyCursors last split CutBuf from, and cutBlock
+	// the rows of the block selection last copied into it.
+	cutParts []string
+	cutBlock []string
 
 	LineStart int
 
 	Dirty bool
 
-	Filename string
-	File     *os.File
+	Indent Indent
+	Format Format
+	// Lossy, if not empty, says why saving would not write the file back
+	// exactly as it was loaded.
+	Lossy string
 
-	stylers []Styler
-}
+	// Wrap breaks lines too long for the screen onto several rows instead
+	// of scrolling sideways; WrapIndent lines those rows up with the
+	// line's own indentation.
+	Wrap       bool
+	WrapIndent bool
 
-func GetPos(gp *gapbuffer.GapBuffer, x, y int) int {
-	xPos := 0
-	yPos := 0
-	if gp.Len() == 0 {
-		return 0
-	}
-	for l1 := 0; l1 < gp.Len(); l1++ {
-		if x <= xPos && y == yPos {
-			return l1
-		}
-		if gp.Get(l1) == '\n' {
-			if x > xPos && y == yPos {
-				return l1
-			}
-			xPos = 0
-			yPos++
-			continue
-		}
-		if gp.Get(l1) == '\t' {
-			xPos = xPos + 4 - (xPos % 4)
-			continue
-		}
-		xPos++
-	}
-	return gp.Len()
-}
+	// Gutter shows line numbers, and the glyphs of any Markers, to the
+	// left of the text; RelativeNumbers numbers lines by their distance
+	// from the cursor instead.
+	Gutter          bool
+	RelativeNumbers bool
 
-func GetCur(gp *gapbuffer.GapBuffer, pos int) (x, y int) {
-	xPos := 0
-	yPos := 0
-	for l1 := 0; l1 < gp.Len(); l1++ {
-		if pos == l1 {
-			return xPos, yPos
-		}
-		if gp.Get(l1) == '\n' {
-			xPos = 0
-			yPos++
-			continue
-		}
-		if gp.Get(l1) == '\t' {
-			xPos = xPos + 4 - (xPos % 4)
-			continue
-		}
-		xPos++
-	}
-	return xPos, yPos
-}
+	Filename string
+	// Backup keeps the previous contents of the file, with BackupSuffix
+	// added to its name, each time it is saved.
+	Backup bool
+
+	// OnRightClick, if set, is called after a right click in a Go file
+	// has moved the cursor to the clicked position.
+	OnRightClick func()
+	// OnCtrlClick, if set, is called after a Ctrl+click has moved the
+	// cursor to the clicked position.
+	OnCtrlClick func()
+	// OnSave, if set, is called after the buffer is written to its file.
+	OnSave func()
 
-func GetHeight(gp *gapbuffer.GapBuffer) (h int) {
-	for l1 := 0; l1 < gp.Len(); l1++ {
-		if gp.Get(l1) == '\n' {
-			h++
-		}
-	}
-	return
+	stylers []Styler
+	markers []Marker
+	// parked are the states of views of the buffer other than the one
+	// driving it.
+	parked []*parked
+
+	// disk is the version of the file last loaded or saved, and seen the
+	// last version of it PollDisk reported.
+	disk, seen Stamp
+
+	hist  history
+	lines lineIndex
+	rev   int
 }
 
 func (b *Buffer) Height() int {
-	return GetHeight(b.GB)
+	return b.LineCount() - 1
 }
 
 func (b *Buffer) Pos() int {
-	return GetPos(b.GB, b.CurX, b.CurY)
+	return b.GetPos(b.CurX, b.CurY)
 }
 
 func (b *Buffer) SetPos(p int) {
-	b.CurX, b.CurY = GetCur(b.GB, p)
+	b.CurX, b.CurY = b.GetCur(p)
 }
 
-func (b *Buffer) GetCur(p int) (x, y int) {
-	return GetCur(b.GB, p)
+// IsGo reports whether the buffer's file is Go source.
+func (b *Buffer) IsGo() bool {
+	return filepath.Ext(b.Filename) == ".go"
+}
+
+// Rev returns a number that changes whenever the contents of the buffer do.
+func (b *Buffer) Rev() int {
+	return b.rev
+}
+
+// Text returns the contents of the buffer.
+func (b *Buffer) Text() string {
+	var sb strings.Builder
+	b.GB.WriteTo(&sb)
+	return sb.String()
 }
 
 func New(buf []rune) *Buffer {
-	return &Buffer{GB: gapbuffer.New(buf), Sel: -1}
+	b := &Buffer{GB: gapbuffer.New(buf), Sel: -1, Indent: DefaultIndent, Format: DefaultFormat}
+	b.lines.build(b.GB)
+	return b
 }
 
 func (b *Buffer) Load(buf []rune) {
 	b.GB = gapbuffer.New(buf)
+	b.lines.build(b.GB)
+	b.rev++
 	b.CurX, b.CurY = 0, 0
-	b.Scroll = 0
+	b.Scroll, b.XScroll = 0, 0
 	b.Dirty = false
 	b.Filename = ""
-	b.File = nil
+	b.Lossy = ""
 	b.Sel = -1
+	b.Cursors = nil
+	b.Block = false
+	b.resetHistory()
 	for _, s := range b.stylers {
 		s.Clear()
 	}
 }
 
+// SaveFile writes the buffer to its file, replacing it atomically. It
+// refuses, with ErrChanged, if the file has been changed since it was
+// loaded or saved.
 func (b *Buffer) SaveFile() error {
-	if b.File == nil && b.Filename != "" {
-		f, err := os.Create(b.Filename)
-		if err != nil {
-			return err
-		}
-		b.File = f
+	if b.Filename == "" {
+		return ErrNoFilename
 	}
-	b.File.Seek(0, os.SEEK_SET)
-	b.File.Truncate(0)
-	b.GB.WriteTo(b.File)
-	b.Dirty = false
-	return nil
+	if b.Changed() {
+		return ErrChanged
+	}
+	return b.ForceSaveFile()
 }
 
+// SaveFileAs saves the buffer under a new name. It keeps its line endings
+// and encoding, except where the EditorConfig files there ask otherwise.
+// The buffer keeps its old name and format if the save fails.
 func (b *Buffer) SaveFileAs(filename string) error {
-	if b.File != nil {
-		b.File.Close()
-		b.File = nil
-	}
-	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0666)
-	if err != nil {
+	oldName, oldFormat, oldIndent := b.Filename, b.Format, b.Indent
+	b.Filename = filename
+	b.configureIndent(b.lookupConfig(filename))
+	if err := b.ForceSaveFile(); err != nil {
+		b.Filename, b.Format, b.Indent = oldName, oldFormat, oldIndent
 		return err
 	}
-	b.File = f
-	return b.SaveFile()
+	return nil
 }
 
 func (b *Buffer) LoadFile(filename string) {
-	if b.File != nil {
-		b.File.Close()
-		b.File = nil
+	b.Format = DefaultFormat
+	props := b.lookupConfig(filename)
+	data, err := ioutil.ReadFile(filename)
+	b.disk = Stamp{}
+	if fi, serr := os.Stat(filename); err == nil && serr == nil {
+		b.disk = stampOf(fi, data)
 	}
-	f, err := os.OpenFile(filename, os.O_RDWR, 0666)
+	b.seen = b.disk
 	if err == nil {
-		b.File = f
-		data, _ := ioutil.ReadAll(b.File)
-		b.GB = gapbuffer.New([]rune(string([]byte(data))))
+		b.detect(data, props)
+		b.GB = gapbuffer.New(b.decode(data))
+		b.lines.build(b.GB)
+		b.Lossy = b.lossiness(data)
 	} else {
 		b.GB = gapbuffer.New(nil)
+		b.lines.build(b.GB)
+		b.Lossy = ""
 	}
+	b.rev++
 	b.CurX, b.CurY = 0, 0
-	b.Scroll = 0
+	b.Scroll, b.XScroll = 0, 0
 	b.Dirty = false
 	b.Sel = -1
+	b.Cursors = nil
+	b.Block = false
 	b.Filename = filename
+	b.Indent = DefaultIndent
+	if indent, ok := DetectIndent([]rune(b.Text())); ok {
+		b.Indent = indent
+	}
+	b.configureIndent(props)
+	b.resetHistory()
 	for _, s := range b.stylers {
 		s.Clear()
 	}
 }
 
 func (b *Buffer) Update(buf []rune) {
-	b.GB = gapbuffer.New(buf)
-	b.Dirty = true
+	b.BeginEdit()
+	defer b.EndEdit()
+	b.Remove(0, b.GB.Len())
+	b.InsertAt(0, buf)
+	b.Cursors = nil
+	b.Block = false
 	for _, s := range b.stylers {
 		s.Clear()
 	}
@@ -198,81 +235,68 @@ func (b *Buffer) Update(buf []rune) {
 func (b *Buffer) Render(r core.Rect) {
 	for l1 := r.Y; l1 < r.Y+r.H; l1++ {
 		for l2 := r.X; l2 < r.X+r.W; l2++ {
-			termbox.SetCell(l2, l1, ' ', termbox.ColorDefault, termbox.ColorDefault)
+			core.SetCell(l2, l1, ' ', termbox.ColorDefault, termbox.ColorDefault)
 		}
 	}
 
-	if b.Scroll > b.CurY {
-		b.Scroll = b.CurY
-	}
-
-	if b.Scroll < b.CurY-(r.H-1) {
-		b.Scroll = b.CurY - (r.H - 1)
-	}
-
-	l1 := 0
-	lineSkip := b.Scroll
-
-	for ; l1 < b.GB.Len() && lineSkip > 0; l1++ {
-		if b.GB.Get(l1) == '\n' {
-			lineSkip--
-		}
-	}
+	tr := b.textRect(r)
+	b.scrollTo(tr)
+	xs := b.xScroll()
+	sels := b.selections()
 
-	xPos := 0
+	// lines records which line starts on each screen row, for the gutter;
+	// rows that continue a wrapped line, or are past the end, hold -1.
+	lines := make([]int, tr.H)
 	yPos := 0
-	curX := b.CurX
-	curY := b.CurY - b.Scroll
-	curSet := false
-	inSel := false
-	for ; l1 < b.GB.Len(); l1++ {
-		if b.Sel >= 0 && b.Sel == l1 {
-			inSel = !inSel
-		}
-		if yPos >= r.H {
-			break
-		}
-		if !curSet && (curX <= xPos && curY == yPos) {
-			if b.Sel >= 0 {
-				inSel = !inSel
+	for y := b.Scroll; y < b.LineCount() && yPos < tr.H; y++ {
+		for i, rw := range b.lineRows(y, tr.W) {
+			if yPos >= tr.H {
+				break
 			}
-			termbox.SetCursor(r.X+xPos, r.Y+yPos)
-			curSet = true
-		}
-		if b.GB.Get(l1) == '\n' {
-			if !curSet && (curX >= xPos && curY == yPos) {
-				if b.Sel >= 0 {
-					inSel = !inSel
+			lines[yPos] = -1
+			if i == 0 {
+				lines[yPos] = y
+			}
+			col := rw.col
+			for l1 := rw.start; l1 < rw.end; l1++ {
+				ch := b.GB.Get(l1)
+				x := rw.indent + col - rw.col - xs
+				col = b.advance(col, ch)
+				if ch == '\t' || x < 0 || x >= tr.W {
+					continue
 				}
-				termbox.SetCursor(r.X+xPos, r.Y+yPos)
-				curSet = true
+				fg, bg := termbox.ColorDefault, termbox.ColorDefault
+				if sels.contain(l1) {
+					fg, bg = termbox.ColorBlack, termbox.ColorWhite
+				}
+				for _, styler := range b.stylers {
+					fg, bg = styler.Style(l1, fg, bg)
+				}
+				core.SetCell(tr.X+x, tr.Y+yPos, ch, fg, bg)
 			}
-			/*if !curSet && yPos+1 >= r.H {
-				termbox.SetCursor(r.X+xPos, r.Y+yPos)
-			}*/
-			xPos = 0
 			yPos++
-			continue
 		}
-		if b.GB.Get(l1) == '\t' {
-			xPos = xPos + 4 - (xPos % 4)
+	}
+	for ; yPos < tr.H; yPos++ {
+		lines[yPos] = -1
+	}
+	b.renderGutter(r, lines)
+
+	// The terminal has only the one cursor, so the others are drawn as
+	// reversed cells.
+	for _, c := range b.extraCursors() {
+		x, y := b.ScreenPos(r, c.Pos)
+		if x < tr.X || x >= tr.X+tr.W || y < tr.Y || y >= tr.Y+tr.H {
 			continue
 		}
-		fg, bg := termbox.ColorDefault, termbox.ColorDefault
-		if inSel {
-			fg, bg = termbox.ColorBlack, termbox.ColorWhite
-		}
-		for _, styler := range b.stylers {
-			fg, bg = styler.Style(l1, fg, bg)
+		ch := ' '
+		if c.Pos < b.GB.Len() && b.GB.Get(c.Pos) != '\n' && b.GB.Get(c.Pos) != '\t' {
+			ch = b.GB.Get(c.Pos)
 		}
-
-		termbox.SetCell(r.X+xPos, r.Y+yPos, b.GB.Get(l1), fg, bg)
-		xPos++
-	}
-	if !curSet {
-		termbox.SetCursor(r.X+xPos, r.Y+yPos)
+		core.SetCell(x, y, ch, termbox.ColorDefault|termbox.AttrReverse, termbox.ColorDefault)
 	}
 
+	core.SetCursor(b.ScreenPos(r, b.Pos()))
 }
 
 func (b *Buffer) AddStyler(s Styler) {
@@ -284,228 +308,230 @@ func (b *Buffer) GetStylers() []Styler {
 }
 
 func (b *Buffer) Insert(ch rune) {
-	curPos := GetPos(b.GB, b.CurX, b.CurY)
-	b.GB.Insert(curPos, ch)
-	for _, s := range b.stylers {
-		s.Insert(curPos)
-	}
+	b.BeginEdit()
+	defer b.EndEdit()
+	curPos := b.Pos()
+	b.InsertAt(curPos, []rune{ch})
 	curPos++
-	b.CurX, b.CurY = GetCur(b.GB, curPos)
-	b.Dirty = true
+	b.SetPos(curPos)
 }
 
 func (b *Buffer) InsertString(str string) {
-	curPos := GetPos(b.GB, b.CurX, b.CurY)
-	for _, ch := range str {
-		b.GB.Insert(curPos, ch)
-		for _, s := range b.stylers {
-			s.Insert(curPos)
-		}
-		curPos++
-	}
-	b.CurX, b.CurY = GetCur(b.GB, curPos)
-	b.Dirty = true
+	b.BeginEdit()
+	defer b.EndEdit()
+	curPos := b.Pos()
+	text := []rune(str)
+	b.InsertAt(curPos, text)
+	curPos += len(text)
+	b.SetPos(curPos)
 }
 
-func (b *Buffer) Handle(r core.Rect, evt termbox.Event) bool {
-	if evt.Type == termbox.EventKey {
-		ch := evt.Ch
-		switch evt.Key {
-		case termbox.KeyArrowLeft:
-			b.Sel = -1
-			curPos := GetPos(b.GB, b.CurX, b.CurY)
-			if curPos > 0 {
-				curPos--
-			}
-			b.CurX, b.CurY = GetCur(b.GB, curPos)
-			return true
-		case termbox.KeyArrowRight:
-			b.Sel = -1
-			curPos := GetPos(b.GB, b.CurX, b.CurY)
-			if curPos < b.GB.Len() {
-				curPos++
-			}
-			b.CurX, b.CurY = GetCur(b.GB, curPos)
-			return true
-		case termbox.KeyArrowUp:
-			b.Sel = -1
-			if b.CurY > 0 {
-				b.CurY--
-			}
+// key handles a key press at the primary cursor.
+func (b *Buffer) key(r core.Rect, evt termbox.Event) bool {
+	ch := evt.Ch
+	switch evt.Key {
+	case termbox.KeyArrowLeft:
+		b.motion(evt)
+		curPos := b.Pos()
+		if evt.Mod&termbox.ModCtrl != 0 {
+			curPos = b.WordLeft(curPos)
+		} else if curPos > 0 {
+			curPos--
+		}
+		b.SetPos(curPos)
+		return true
+	case termbox.KeyArrowRight:
+		b.motion(evt)
+		curPos := b.Pos()
+		if evt.Mod&termbox.ModCtrl != 0 {
+			curPos = b.WordRight(curPos)
+		} else if curPos < b.GB.Len() {
+			curPos++
+		}
+		b.SetPos(curPos)
+		return true
+	case termbox.KeyArrowUp:
+		b.motion(evt)
+		if b.Wrap {
+			b.moveRows(b.textRect(r), -1)
 			return true
-		case termbox.KeyPgup:
-			b.Sel = -1
-			b.CurY -= 10
-			if b.CurY < 0 {
-				b.CurY = 0
-			}
+		}
+		if b.CurY > 0 {
+			b.CurY--
+		}
+		return true
+	case termbox.KeyPgup:
+		b.motion(evt)
+		b.CurY -= 10
+		if b.CurY < 0 {
+			b.CurY = 0
+		}
+		return true
+	case termbox.KeyArrowDown:
+		b.motion(evt)
+		if b.Wrap {
+			b.moveRows(b.textRect(r), 1)
 			return true
-		case termbox.KeyArrowDown:
-			b.Sel = -1
-			if b.CurY < GetHeight(b.GB) {
-				b.CurY++
-			}
+		}
+		if b.CurY < b.Height() {
+			b.CurY++
+		}
+		return true
+	case termbox.KeyPgdn:
+		b.motion(evt)
+		b.CurY += 10
+		h := b.Height()
+		if b.CurY > h {
+			b.CurY = h
+		}
+		return true
+	case termbox.KeyHome:
+		b.motion(evt)
+		if evt.Mod&termbox.ModCtrl != 0 {
+			b.SetPos(0)
 			return true
-		case termbox.KeyPgdn:
-			b.Sel = -1
-			b.CurY += 10
-			h := GetHeight(b.GB)
-			if b.CurY > h {
-				b.CurY = h
-			}
+		}
+		b.CurX = 0
+		return true
+	case termbox.KeyEnd:
+		b.motion(evt)
+		if evt.Mod&termbox.ModCtrl != 0 {
+			b.SetPos(b.GB.Len())
 			return true
-		case termbox.KeyHome:
-			b.Sel = -1
-			b.CurX = 0
+		}
+		b.SetPos(b.LineEnd(b.CurY))
+		return true
+	case termbox.KeyEnter:
+		b.DeleteSelection()
+		indent := b.indentAt(b.Pos())
+		b.Insert('\n')
+		b.InsertString(string(indent))
+		break
+	case termbox.KeySpace:
+		ch = ' '
+	case termbox.KeyTab:
+		b.DeleteSelection()
+		b.InsertString(string(b.tab(b.Pos())))
+		return true
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if b.DeleteSelection() {
 			return true
-		case termbox.KeyEnd:
-			b.Sel = -1
-			b.Sel = -1
-			curPos := b.Pos()
-			for curPos < b.GB.Len() && b.GB.Get(curPos) != '\n' {
-				curPos++
-			}
-			b.SetPos(curPos)
-		case termbox.KeyEnter:
-			b.Sel = -1
-			pos := b.Pos()-1
-			tabs := 0
-			for pos >= 0 && b.GB.Get(pos) != '\n' {
-				if b.GB.Get(pos) == '\t' {
-					tabs++
-				} else {
-					tabs = 0
-				}
-				pos--
-			}
-			b.Insert('\n')
-			for tabs > 0 {
-				b.Insert('\t')
-				tabs--
-			}
+		}
+		curPos := b.Pos()
+		if curPos <= 0 {
 			break
-		case termbox.KeySpace:
-			ch = ' '
-		case termbox.KeyTab:
-			ch = '\t'
-		case termbox.KeyBackspace, termbox.KeyBackspace2:
-			if b.Sel != -1 {
-				curPos := b.Pos()
-				pos1, pos2 := b.Sel, curPos
-				if pos1 > pos2 {
-					pos1, pos2 = pos2, pos1
-				}
-				b.GB.Cut(pos1, pos2-pos1)
-				b.Sel = -1
-				break
-			}
-			b.Sel = -1
-			curPos := GetPos(b.GB, b.CurX, b.CurY)
-			if curPos <= 0 {
-				break
-			}
-			b.GB.Delete(curPos)
-			for _, s := range b.stylers {
-				s.Delete(curPos)
-			}
-			curPos--
-			b.CurX, b.CurY = GetCur(b.GB, curPos)
-			b.Dirty = true
+		}
+		curPos--
+		b.Remove(curPos, 1)
+		b.SetPos(curPos)
+		return true
+	case termbox.KeyDelete:
+		if b.DeleteSelection() {
 			return true
-		case termbox.KeyDelete:
-			b.Sel = -1
-			curPos := GetPos(b.GB, b.CurX, b.CurY)
-			if b.GB.Len()-curPos <= 0 {
-				break
-			}
-			b.GB.Delete(curPos + 1)
-			for _, s := range b.stylers {
-				s.Delete(curPos + 1)
-			}
-			b.CurX, b.CurY = GetCur(b.GB, curPos)
-			b.Dirty = true
+		}
+		curPos := b.Pos()
+		if b.GB.Len()-curPos <= 0 {
+			break
+		}
+		b.Remove(curPos, 1)
+		b.SetPos(curPos)
+		return true
+	case termbox.KeyCtrlK:
+		if b.Sel >= 0 {
+			b.CutBuf = b.SelectedText()
+			b.LastCut = -1
+			b.DeleteSelection()
 			return true
-		case termbox.KeyCtrlK:
-			for _, s := range b.stylers {
-				s.Clear()
-			}
-			if b.Sel >= 0 {
-				curPos := b.Pos()
-				pos1, pos2 := b.Sel, curPos
-				if pos1 > pos2 {
-					pos1, pos2 = pos2, pos1
-				}
-				b.CutBuf = []rune(b.GB.Cut(pos1, pos2-pos1))
-				b.LastCut = -1
-				b.SetPos(pos1)
-				b.Sel = -1
-				return true
-			}
-
-			curPos := GetPos(b.GB, b.CurX, b.CurY)
-			if curPos != b.LastCut {
-				b.CutBuf = b.CutBuf[:0]
-			}
+		}
 
-			for curPos > 0 && b.GB.Get(curPos-1) != '\n' {
-				curPos--
-			}
-			if curPos >= b.GB.Len() {
-				return true
-			}
+		curPos := b.Pos()
+		if curPos != b.LastCut {
+			b.CutBuf = b.CutBuf[:0]
+		}
 
-			b.CutBuf = append(b.CutBuf, b.GB.Get(curPos))
-			b.GB.Delete(curPos)
-			for curPos < b.GB.Len() && b.GB.Get(curPos-1) != '\n' {
-				b.CutBuf = append(b.CutBuf, b.GB.Get(curPos))
-				b.GB.Delete(curPos)
-			}
-			b.LastCut = curPos
-			b.CurX, b.CurY = GetCur(b.GB, curPos)
-			b.Dirty = true
-			return true
-		case termbox.KeyCtrlU:
-			b.Sel = -1
-			for _, ch := range b.CutBuf {
-				b.Insert(ch)
-			}
-			return true
-		case termbox.KeyCtrlC:
-			if b.Sel >= 0 {
-				curPos := b.Pos()
-				pos1, pos2 := b.Sel, curPos
-				if pos1 > pos2 {
-					pos1, pos2 = pos2, pos1
-				}
-				b.CutBuf = b.CutBuf[:0]
-				for l1 := pos1; l1 <= pos2; l1++ {
-					b.CutBuf = append(b.CutBuf, b.GB.Get(l1))
-				}
-				b.LastCut = -1
-			}
-			return true
-		case termbox.KeyCtrlV:
-			b.Sel = -1
-			for _, ch := range b.CutBuf {
-				b.Insert(ch)
-			}
+		for curPos > 0 && b.GB.Get(curPos-1) != '\n' {
+			curPos--
+		}
+		if curPos >= b.GB.Len() {
 			return true
 		}
-		if ch != '\x00' {
-			b.Sel = -1
-			b.Insert(ch)
+
+		end := curPos
+		for end < b.GB.Len() && b.GB.Get(end) != '\n' {
+			end++
+		}
+		if end < b.GB.Len() {
+			end++
+		}
+		b.CutBuf = append(b.CutBuf, b.Remove(curPos, end-curPos)...)
+		b.LastCut = curPos
+		b.SetPos(curPos)
+		return true
+	case termbox.KeyCtrlU:
+		b.DeleteSelection()
+		b.InsertString(string(b.CutBuf))
+		return true
+	case termbox.KeyCtrlC:
+		if b.Sel >= 0 {
+			b.CutBuf = b.SelectedText()
+			b.LastCut = -1
+		}
+		return true
+	case termbox.KeyCtrlV:
+		b.DeleteSelection()
+		b.InsertString(string(b.CutBuf))
+		return true
+	}
+	if ch != '\x00' {
+		b.DeleteSelection()
+		b.Insert(ch)
+		return true
+	}
+	return false
+}
+
+func (b *Buffer) Handle(r core.Rect, evt termbox.Event) bool {
+	if evt.Type == termbox.EventKey {
+		if b.blockKey(evt) || b.cursorKey(evt) {
 			return true
 		}
+		b.BeginEdit()
+		defer b.EndEdit()
+		handled := false
+		b.eachCursor(func(int) {
+			if b.key(r, evt) {
+				handled = true
+			}
+		})
+		return handled
 	}
 	if evt.Type == termbox.EventMouse && r.CheckEvent(evt) {
 		switch evt.Key {
 		case termbox.MouseLeft:
-			curPos := GetPos(b.GB, evt.MouseX-r.X, evt.MouseY+b.Scroll-r.Y)
-			if evt.Mod == termbox.ModMotion {
+			if evt.Mod&termbox.ModAlt != 0 {
+				b.blockMouse(r, evt)
+				return true
+			}
+			b.Cursors = nil
+			b.Block = false
+			curPos := b.PosAt(r, evt.MouseX, evt.MouseY)
+			if evt.Mod&termbox.ModMotion != 0 {
 				b.Sel = curPos
 			} else {
 				b.Sel = -1
-				b.CurX, b.CurY = GetCur(b.GB, curPos)
+				b.SetPos(curPos)
+				if evt.Mod&termbox.ModCtrl != 0 && b.OnCtrlClick != nil {
+					b.OnCtrlClick()
+				}
+			}
+			return true
+		case termbox.MouseRight:
+			b.Cursors = nil
+			b.Block = false
+			b.Sel = -1
+			b.SetPos(b.PosAt(r, evt.MouseX, evt.MouseY))
+			if b.OnRightClick != nil && b.IsGo() {
+				b.OnRightClick()
 			}
 			return true
 		case termbox.MouseWheelUp:
@@ -518,7 +544,7 @@ func (b *Buffer) Handle(r core.Rect, evt termbox.Event) bool {
 		case termbox.MouseWheelDown:
 			b.Sel = -1
 			b.CurY += 2
-			h := GetHeight(b.GB)
+			h := b.Height()
 			if b.CurY > h {
 				b.CurY = h
 			}
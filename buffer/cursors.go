@@ -0,0 +1,236 @@
+package buffer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/andyleap/termbox-go"
+)
+
+// Cursor is an extra cursor: a position and a selection anchor, -1 if
+// nothing is selected, just like the primary cursor's Pos and Sel.
+type Cursor struct {
+	Pos, Sel int
+}
+
+// shiftPos returns where offset p ends up when n runes are inserted at
+// pos, or removed from it when n is negative.
+func shiftPos(p, pos, n int) int {
+	switch {
+	case p < pos:
+		return p
+	case n > 0:
+		return p + n
+	case p >= pos-n:
+		return p + n
+	}
+	return pos
+}
+
+// shiftCursors keeps the extra cursors on the same text when n runes are
+// inserted at pos, or removed from it when n is negative.
+func (b *Buffer) shiftCursors(pos, n int) {
+	for i, c := range b.Cursors {
+		b.Cursors[i].Pos = shiftPos(c.Pos, pos, n)
+		if c.Sel >= 0 {
+			b.Cursors[i].Sel = shiftPos(c.Sel, pos, n)
+		}
+	}
+}
+
+// eachCursor calls fn once for every cursor, in order through the text,
+// with that cursor swapped in as the primary one and i its place in that
+// order. Edits made at one cursor move the others along with the text, and
+// they are all undone together.
+func (b *Buffer) eachCursor(fn func(i int)) {
+	if len(b.Cursors) == 0 {
+		fn(0)
+		return
+	}
+	b.BeginEdit()
+	defer b.EndEdit()
+	b.Cursors = append([]Cursor{{Pos: b.Pos(), Sel: b.Sel}}, b.Cursors...)
+	order := make([]int, len(b.Cursors))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return b.Cursors[order[i]].Pos < b.Cursors[order[j]].Pos
+	})
+	for i, c := range order {
+		b.SetPos(b.Cursors[c].Pos)
+		b.Sel = b.Cursors[c].Sel
+		fn(i)
+		b.Cursors[c] = Cursor{Pos: b.Pos(), Sel: b.Sel}
+	}
+	primary := b.Cursors[0]
+	b.Cursors = b.Cursors[1:]
+	b.SetPos(primary.Pos)
+	b.Sel = primary.Sel
+	b.mergeCursors()
+}
+
+// mergeCursors drops extra cursors that have ended up where another one
+// already is.
+func (b *Buffer) mergeCursors() {
+	seen := map[int]bool{b.Pos(): true}
+	cursors := b.Cursors[:0]
+	for _, c := range b.Cursors {
+		if seen[c.Pos] {
+			continue
+		}
+		seen[c.Pos] = true
+		cursors = append(cursors, c)
+	}
+	b.Cursors = cursors
+}
+
+// AddCursor leaves a cursor where the primary one is and moves the primary
+// cursor n lines down, or up if n is negative, keeping its column.
+func (b *Buffer) AddCursor(n int) {
+	y := b.CurY + n
+	if y < 0 || y > b.Height() {
+		return
+	}
+	b.Cursors = append(b.Cursors, Cursor{Pos: b.Pos(), Sel: b.Sel})
+	b.CurY = y
+	b.Sel = -1
+	b.mergeCursors()
+}
+
+// AddNextOccurrence selects the word at the cursor if nothing is selected.
+// Otherwise it leaves a cursor on the selection and moves the primary one
+// to select the next occurrence of the same text, wrapping round the end
+// of the buffer. It reports whether anything changed.
+func (b *Buffer) AddNextOccurrence() bool {
+	start, end, ok := b.Selection()
+	if !ok {
+		pos := b.Pos()
+		start, end = pos, pos
+		for start > 0 && isWordChar(b.GB.Get(start-1)) {
+			start--
+		}
+		for end < b.GB.Len() && isWordChar(b.GB.Get(end)) {
+			end++
+		}
+		if start == end {
+			return false
+		}
+		b.Sel = start
+		b.SetPos(end)
+		return true
+	}
+
+	needle := b.SelectedText()
+	taken := map[int]bool{start: true}
+	for _, c := range b.Cursors {
+		if c.Sel >= 0 && c.Sel < c.Pos {
+			taken[c.Sel] = true
+		} else if c.Sel >= 0 {
+			taken[c.Pos] = true
+		}
+	}
+	n := b.GB.Len() - len(needle) + 1
+	for l1 := 0; l1 < n; l1++ {
+		p := (end + l1) % n
+		if taken[p] || !b.matchAt(p, needle) {
+			continue
+		}
+		b.Cursors = append(b.Cursors, Cursor{Pos: b.Pos(), Sel: b.Sel})
+		b.Sel = p
+		b.SetPos(p + len(needle))
+		return true
+	}
+	return false
+}
+
+func (b *Buffer) matchAt(pos int, text []rune) bool {
+	for i, ch := range text {
+		if b.GB.Get(pos+i) != ch {
+			return false
+		}
+	}
+	return true
+}
+
+// ClearCursors removes every cursor but the primary one, reporting whether
+// there were any.
+func (b *Buffer) ClearCursors() bool {
+	had := len(b.Cursors) > 0
+	b.Cursors = nil
+	return had
+}
+
+// EachCursor calls fn with each cursor in turn as the primary one, so an
+// edit written for a single cursor is made at all of them.
+func (b *Buffer) EachCursor(fn func()) {
+	b.eachCursor(func(int) { fn() })
+}
+
+// copyCursors puts the text selected at every cursor in the cut buffer, a
+// line each, remembering the pieces so pasteCursors can hand them back out
+// one per cursor.
+func (b *Buffer) copyCursors() {
+	var parts []string
+	b.eachCursor(func(int) {
+		parts = append(parts, string(b.SelectedText()))
+	})
+	b.CutBuf = []rune(strings.Join(parts, "\n"))
+	b.LastCut = -1
+	b.cutParts = parts
+}
+
+// pasteCursors pastes the cut buffer at every cursor, replacing what they
+// have selected. If it still holds what copyCursors left there, from as
+// many cursors as there are now, each cursor gets its own piece.
+func (b *Buffer) pasteCursors() {
+	parts := b.cutParts
+	if len(parts) != len(b.Cursors)+1 || strings.Join(parts, "\n") != string(b.CutBuf) {
+		parts = nil
+	}
+	b.eachCursor(func(i int) {
+		b.DeleteSelection()
+		if parts != nil {
+			b.InsertString(parts[i])
+			return
+		}
+		b.InsertString(string(b.CutBuf))
+	})
+}
+
+// cursorKey handles the keys that add and remove cursors, and those that
+// act on all the cursors together rather than on each in turn.
+func (b *Buffer) cursorKey(evt termbox.Event) bool {
+	ctrlAlt := evt.Mod&(termbox.ModCtrl|termbox.ModAlt) == termbox.ModCtrl|termbox.ModAlt
+	switch {
+	case evt.Key == termbox.KeyCtrlD:
+		b.AddNextOccurrence()
+		return true
+	case evt.Key == termbox.KeyArrowUp && ctrlAlt:
+		b.AddCursor(-1)
+		return true
+	case evt.Key == termbox.KeyArrowDown && ctrlAlt:
+		b.AddCursor(1)
+		return true
+	case evt.Key == termbox.KeyEsc:
+		return b.ClearCursors()
+	}
+	if len(b.Cursors) == 0 {
+		return false
+	}
+	switch evt.Key {
+	case termbox.KeyCtrlC:
+		b.copyCursors()
+		return true
+	case termbox.KeyCtrlK:
+		b.BeginEdit()
+		defer b.EndEdit()
+		b.copyCursors()
+		b.eachCursor(func(int) { b.DeleteSelection() })
+		return true
+	case termbox.KeyCtrlV, termbox.KeyCtrlU:
+		b.pasteCursors()
+		return true
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package buffer
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// ErrChanged is returned by SaveFile when the file has been changed by
+// something else since it was loaded or last saved.
+var ErrChanged = errors.New("file has changed on disk")
+
+// Stamp identifies a version of a file on disk.
+type Stamp struct {
+	ModTime time.Time
+	Size    int64
+	Sum     [sha256.Size]byte
+}
+
+func stampOf(fi os.FileInfo, data []byte) Stamp {
+	return Stamp{
+		ModTime: fi.ModTime(),
+		Size:    fi.Size(),
+		Sum:     sha256.Sum256(data),
+	}
+}
+
+// diskStamp returns the stamp of the buffer's file as it is now, reusing
+// known without reading the file if its time and size still match. It
+// reports false if there is no file.
+func (b *Buffer) diskStamp(known Stamp) (Stamp, bool) {
+	if b.Filename == "" {
+		return Stamp{}, false
+	}
+	fi, err := os.Stat(b.Filename)
+	if err != nil {
+		return Stamp{}, false
+	}
+	if fi.ModTime().Equal(known.ModTime) && fi.Size() == known.Size {
+		return known, true
+	}
+	data, err := ioutil.ReadFile(b.Filename)
+	if err != nil {
+		return Stamp{}, false
+	}
+	return stampOf(fi, data), true
+}
+
+// Changed reports whether the buffer's file now holds something other than
+// what was last loaded or saved. A file that has only been touched is not
+// changed.
+func (b *Buffer) Changed() bool {
+	// seen is compared against first as, once the file has changed, it is
+	// the version most likely to still be there.
+	s, ok := b.diskStamp(b.seen)
+	if !ok {
+		return false
+	}
+	if s.Sum == b.disk.Sum {
+		b.disk, b.seen = s, s
+		return false
+	}
+	return true
+}
+
+// PollDisk reports whether the buffer's file has changed in a way it has
+// not reported before, so each change is only raised once.
+func (b *Buffer) PollDisk() bool {
+	if !b.Changed() {
+		return false
+	}
+	s, _ := b.diskStamp(b.seen)
+	if s.Sum == b.seen.Sum {
+		b.seen = s
+		return false
+	}
+	b.seen = s
+	return true
+}
+
+// DiskText returns what the buffer's file holds now, decoded as the buffer
+// was.
+func (b *Buffer) DiskText() (string, error) {
+	data, err := ioutil.ReadFile(b.Filename)
+	if err != nil {
+		return "", err
+	}
+	return string(b.decode(data)), nil
+}
+
+// Reload replaces the buffer's contents with its file's, as a single edit
+// that can be undone.
+func (b *Buffer) Reload() error {
+	fi, err := os.Stat(b.Filename)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(b.Filename)
+	if err != nil {
+		return err
+	}
+	pos := b.Pos()
+	b.Update(b.decode(data))
+	if pos > b.GB.Len() {
+		pos = b.GB.Len()
+	}
+	b.SetPos(pos)
+	b.Sel = -1
+	b.Lossy = b.lossiness(data)
+	b.disk = stampOf(fi, data)
+	b.seen = b.disk
+	b.markClean()
+	return nil
+}
+
+// ForceSaveFile saves the buffer even if its file has changed on disk.
+func (b *Buffer) ForceSaveFile() error {
+	if b.Filename == "" {
+		return ErrNoFilename
+	}
+	b.tidy()
+	data := b.encode()
+	if err := writeFile(b.Filename, data, b.Backup); err != nil {
+		return err
+	}
+	if fi, err := os.Stat(b.Filename); err == nil {
+		b.disk = stampOf(fi, data)
+		b.seen = b.disk
+	}
+	b.Lossy = ""
+	b.markClean()
+	if b.OnSave != nil {
+		b.OnSave()
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package buffer
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/andyleap/editor/editorconfig"
+)
+
+// Format describes how a buffer is stored on disk, and the tidying done to
+// it on save.
+type Format struct {
+	// EOL is the line ending written in place of each newline.
+	EOL string
+	// Charset is an EditorConfig charset: utf-8, utf-8-bom, latin1,
+	// utf-16le or utf-16be.
+	Charset string
+
+	TrimTrailingWhitespace bool
+	InsertFinalNewline     bool
+}
+
+var DefaultFormat = Format{EOL: "\n", Charset: "utf-8"}
+
+const bom = "\uFEFF"
+
+var eols = map[string]string{
+	"lf":   "\n",
+	"crlf": "\r\n",
+	"cr":   "\r",
+}
+
+// lookupConfig finds the EditorConfig properties for filename and applies
+// those describing its format, returning them all. Parts of the format
+// they don't set are left alone.
+func (b *Buffer) lookupConfig(filename string) editorconfig.Properties {
+	props, err := editorconfig.Lookup(filename)
+	if err != nil {
+		return nil
+	}
+	if eol, ok := eols[props["end_of_line"]]; ok {
+		b.Format.EOL = eol
+	}
+	switch cs := props["charset"]; cs {
+	case "utf-8", "utf-8-bom", "latin1", "utf-16le", "utf-16be":
+		b.Format.Charset = cs
+	}
+	if v, ok := props.Bool("trim_trailing_whitespace"); ok {
+		b.Format.TrimTrailingWhitespace = v
+	}
+	if v, ok := props.Bool("insert_final_newline"); ok {
+		b.Format.InsertFinalNewline = v
+	}
+	return props
+}
+
+// configureIndent applies the EditorConfig indentation properties, which
+// take precedence over any indentation detected from the text.
+func (b *Buffer) configureIndent(props editorconfig.Properties) {
+	if n, ok := props.Int("tab_width"); ok {
+		b.Indent.TabWidth = n
+	}
+	if n, ok := props.Int("indent_size"); ok {
+		b.Indent.IndentSize = n
+	} else if props["indent_size"] == "tab" {
+		b.Indent.IndentSize = b.Indent.TabWidth
+	}
+	switch props["indent_style"] {
+	case "space":
+		b.Indent.UseSpaces = true
+	case "tab":
+		b.Indent.UseSpaces = false
+	}
+}
+
+// decode turns the contents of a file into text with plain newlines.
+func (b *Buffer) decode(data []byte) []rune {
+	var text []rune
+	switch b.Format.Charset {
+	case "latin1":
+		text = make([]rune, len(data))
+		for i, c := range data {
+			text[i] = rune(c)
+		}
+	case "utf-16le", "utf-16be":
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			lo, hi := data[2*i], data[2*i+1]
+			if b.Format.Charset == "utf-16be" {
+				lo, hi = hi, lo
+			}
+			units[i] = uint16(lo) | uint16(hi)<<8
+		}
+		if len(units) > 0 && units[0] == 0xFEFF {
+			units = units[1:]
+		}
+		text = utf16.Decode(units)
+	default:
+		s := strings.TrimPrefix(string(data), bom)
+		text = []rune(s)
+	}
+	// CRLF always becomes a plain newline, even in a file that mostly
+	// uses LF, so that stray carriage returns are not shown as text.
+	s := strings.Replace(string(text), "\r\n", "\n", -1)
+	if b.Format.EOL == "\r" {
+		s = strings.Replace(s, "\r", "\n", -1)
+	}
+	return []rune(s)
+}
+
+// detect works out the line endings and encoding of data, where they are
+// not already set by props.
+func (b *Buffer) detect(data []byte, props editorconfig.Properties) {
+	if _, ok := eols[props["end_of_line"]]; !ok {
+		crlf := bytes.Count(data, []byte("\r\n"))
+		lf := bytes.Count(data, []byte("\n")) - crlf
+		cr := bytes.Count(data, []byte("\r")) - crlf
+		switch {
+		case crlf > lf && crlf >= cr:
+			b.Format.EOL = "\r\n"
+		case cr > lf && cr > crlf:
+			b.Format.EOL = "\r"
+		}
+	}
+	if props["charset"] == "" {
+		switch {
+		case bytes.HasPrefix(data, []byte(bom)):
+			b.Format.Charset = "utf-8-bom"
+		case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+			b.Format.Charset = "utf-16le"
+		case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+			b.Format.Charset = "utf-16be"
+		}
+	}
+}
+
+// lossiness explains why saving the buffer as loaded from data would not
+// write data back, or returns "" if it would.
+func (b *Buffer) lossiness(data []byte) string {
+	if bytes.Equal(b.encode(), data) {
+		return ""
+	}
+	switch b.Format.Charset {
+	case "utf-8", "utf-8-bom":
+		if !utf8.Valid(data) {
+			return "is not valid UTF-8"
+		}
+	case "utf-16le", "utf-16be":
+		if len(data)%2 != 0 {
+			return "is not valid " + strings.ToUpper(b.Format.Charset)
+		}
+	}
+	if n := bytes.Count(data, []byte("\r")); n > 0 && n != bytes.Count(data, []byte("\n")) {
+		return "has mixed line endings"
+	}
+	return "could not be read exactly"
+}
+
+// encode returns the buffer as it should be written to its file.
+func (b *Buffer) encode() []byte {
+	s := b.Text()
+	if b.Format.EOL != "" && b.Format.EOL != "\n" {
+		s = strings.Replace(s, "\n", b.Format.EOL, -1)
+	}
+	switch b.Format.Charset {
+	case "latin1":
+		data := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r > 0xFF {
+				r = '?'
+			}
+			data = append(data, byte(r))
+		}
+		return data
+	case "utf-16le", "utf-16be":
+		units := utf16.Encode(append([]rune{0xFEFF}, []rune(s)...))
+		data := make([]byte, 0, 2*len(units))
+		for _, u := range units {
+			if b.Format.Charset == "utf-16be" {
+				data = append(data, byte(u>>8), byte(u))
+			} else {
+				data = append(data, byte(u), byte(u>>8))
+			}
+		}
+		return data
+	case "utf-8-bom":
+		data := make([]byte, 0, len(s)+utf8.UTFMax)
+		data = append(data, bom...)
+		return append(data, s...)
+	}
+	return []byte(s)
+}
+
+// tidy trims trailing whitespace and adds a final newline, if the format
+// asks for them, before the buffer is saved.
+func (b *Buffer) tidy() {
+	if !b.Format.TrimTrailingWhitespace && !b.Format.InsertFinalNewline {
+		return
+	}
+	b.BeginEdit()
+	defer b.EndEdit()
+	pos := b.Pos()
+	if b.Format.TrimTrailingWhitespace {
+		for y := b.Height(); y >= 0; y-- {
+			start, end := b.LineOffset(y), b.LineEnd(y)
+			ws := end
+			for ws > start && (b.GB.Get(ws-1) == ' ' || b.GB.Get(ws-1) == '\t') {
+				ws--
+			}
+			if ws == end {
+				continue
+			}
+			b.Remove(ws, end-ws)
+			if pos > end {
+				pos -= end - ws
+			} else if pos > ws {
+				pos = ws
+			}
+		}
+	}
+	if n := b.GB.Len(); b.Format.InsertFinalNewline && n > 0 && b.GB.Get(n-1) != '\n' {
+		b.InsertAt(n, []rune{'\n'})
+	}
+	b.SetPos(pos)
+}
@@ -0,0 +1,42 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAsKeepsFormat(t *testing.T) {
+	dir := t.TempDir()
+	orig := filepath.Join(dir, "orig.txt")
+	data := bom + "one\r\ntwo\r\n"
+	if err := ioutil.WriteFile(orig, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := New(nil)
+	b.LoadFile(orig)
+
+	copied := filepath.Join(dir, "copy.txt")
+	if err := b.SaveFileAs(copied); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ioutil.ReadFile(copied); string(got) != data {
+		t.Errorf("saved %q, want %q", got, data)
+	}
+
+	// Only what the EditorConfig file sets changes; the BOM stays.
+	sub := filepath.Join(dir, "lf")
+	os.Mkdir(sub, 0755)
+	config := "root = true\n[*]\nend_of_line = lf\n"
+	if err := ioutil.WriteFile(filepath.Join(sub, ".editorconfig"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SaveFileAs(filepath.Join(sub, "copy.txt")); err != nil {
+		t.Fatal(err)
+	}
+	want := bom + "one\ntwo\n"
+	if got, _ := ioutil.ReadFile(filepath.Join(sub, "copy.txt")); string(got) != want {
+		t.Errorf("saved %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package buffer
+
+import (
+	"strconv"
+
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+// Marker is implemented by components that want to flag lines in the
+// gutter, such as with errors or bookmarks.
+type Marker interface {
+	// Mark returns the glyph to show beside line y, if any.
+	Mark(y int) (ch rune, fg termbox.Attribute, ok bool)
+}
+
+func (b *Buffer) AddMarker(m Marker) {
+	b.markers = append(b.markers, m)
+}
+
+// GutterWidth returns how many columns the gutter takes up: a marker
+// column, the line numbers and a space.
+func (b *Buffer) GutterWidth() int {
+	if !b.Gutter {
+		return 0
+	}
+	return len(strconv.Itoa(b.LineCount())) + 2
+}
+
+// textRect returns the part of r left for the text once the gutter is
+// drawn.
+func (b *Buffer) textRect(r core.Rect) core.Rect {
+	g := b.GutterWidth()
+	if g > r.W {
+		g = r.W
+	}
+	return core.Rect{X: r.X + g, Y: r.Y, W: r.W - g, H: r.H}
+}
+
+// renderGutter draws the gutter beside the text, given which line starts
+// on each row.
+func (b *Buffer) renderGutter(r core.Rect, lines []int) {
+	g := b.GutterWidth()
+	if g == 0 {
+		return
+	}
+	for l1, y := range lines {
+		if y < 0 {
+			continue
+		}
+		n := y + 1
+		fg := termbox.ColorYellow
+		if y == b.CurY {
+			fg |= termbox.AttrBold
+		} else if b.RelativeNumbers {
+			n = y - b.CurY
+			if n < 0 {
+				n = -n
+			}
+		}
+		num := strconv.Itoa(n)
+		for i, ch := range num {
+			if x := g - 1 - len(num) + i; x < r.W {
+				core.SetCell(r.X+x, r.Y+l1, ch, fg, termbox.ColorDefault)
+			}
+		}
+		for _, m := range b.markers {
+			if ch, mfg, ok := m.Mark(y); ok {
+				core.SetCell(r.X, r.Y+l1, ch, mfg, termbox.ColorDefault)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package buffer
+
+// Indent describes how a buffer is indented: how wide a tab is drawn, how
+// far the Tab key indents, and whether it does so with spaces.
+type Indent struct {
+	TabWidth   int
+	IndentSize int
+	UseSpaces  bool
+}
+
+var DefaultIndent = Indent{TabWidth: 4, IndentSize: 4}
+
+func (b *Buffer) tabWidth() int {
+	if b.Indent.TabWidth <= 0 {
+		return DefaultIndent.TabWidth
+	}
+	return b.Indent.TabWidth
+}
+
+func (b *Buffer) indentSize() int {
+	if b.Indent.IndentSize <= 0 {
+		return b.tabWidth()
+	}
+	return b.Indent.IndentSize
+}
+
+// DetectIndent guesses how text is indented from the lines that are. It
+// reports false if there are too few indented lines to tell.
+func DetectIndent(text []rune) (Indent, bool) {
+	tabs, spaces := 0, 0
+	// steps counts how often the indentation of space indented lines
+	// grows by each amount from one line to the next.
+	steps := map[int]int{}
+	prev := 0
+	atStart := true
+	width := 0
+	for _, ch := range append(text, '\n') {
+		if !atStart {
+			if ch == '\n' {
+				atStart, width = true, 0
+			}
+			continue
+		}
+		switch ch {
+		case '\t':
+			tabs++
+			atStart = false
+			prev = 0
+			continue
+		case ' ':
+			width++
+			continue
+		case '\n':
+			width = 0
+			continue
+		}
+		atStart = false
+		if width > 0 {
+			spaces++
+		}
+		if width > prev {
+			steps[width-prev]++
+		}
+		prev = width
+	}
+	if tabs+spaces < 3 {
+		return DefaultIndent, false
+	}
+	if tabs >= spaces {
+		return DefaultIndent, true
+	}
+	size, best := DefaultIndent.IndentSize, 0
+	for step, n := range steps {
+		if step > 1 && step <= 8 && (n > best || n == best && step < size) {
+			size, best = step, n
+		}
+	}
+	return Indent{TabWidth: DefaultIndent.TabWidth, IndentSize: size, UseSpaces: true}, true
+}
+
+// indentAt returns the leading whitespace of the line holding pos, up to
+// pos.
+func (b *Buffer) indentAt(pos int) []rune {
+	var ws []rune
+	for l1 := b.LineOffset(b.LineAt(pos)); l1 < pos; l1++ {
+		ch := b.GB.Get(l1)
+		if ch != ' ' && ch != '\t' {
+			break
+		}
+		ws = append(ws, ch)
+	}
+	return ws
+}
+
+// tab returns what the Tab key inserts at pos.
+func (b *Buffer) tab(pos int) []rune {
+	if !b.Indent.UseSpaces {
+		return []rune{'\t'}
+	}
+	x, _ := b.GetCur(pos)
+	n := b.indentSize() - x%b.indentSize()
+	ws := make([]rune, n)
+	for i := range ws {
+		ws[i] = ' '
+	}
+	return ws
+}
@@ -0,0 +1,221 @@
+package buffer
+
+import (
+	"unicode"
+
+	"github.com/andyleap/editor/core"
+)
+
+// row is the part of a line drawn on one screen row: the runes from start
+// up to end, the first of which sits at column col of the line and is
+// drawn indent cells from the left of the text area.
+type row struct {
+	start, end int
+	col        int
+	indent     int
+}
+
+// lineRows lays line y out on screen rows of width w. Without Wrap every
+// line is a single row.
+func (b *Buffer) lineRows(y, w int) []row {
+	start, end := b.LineOffset(y), b.LineEnd(y)
+	if !b.Wrap || w <= 0 {
+		return []row{{start: start, end: end}}
+	}
+
+	indent := 0
+	if b.WrapIndent {
+		for l1 := start; l1 < end && unicode.IsSpace(b.GB.Get(l1)); l1++ {
+			indent = b.advance(indent, b.GB.Get(l1))
+		}
+		if indent > w/2 {
+			indent = 0
+		}
+	}
+
+	// Breaking inside the line's own indentation would only leave an
+	// empty row, so the first break is after its first word.
+	text := start
+	for text < end && unicode.IsSpace(b.GB.Get(text)) {
+		text++
+	}
+
+	var rows []row
+	cur := row{start: start}
+	// brk and brkCol remember the last place the row could be broken
+	// after a space, so words are kept whole where they fit.
+	brk, brkCol := -1, 0
+	col := 0
+	for l1 := start; l1 < end; l1++ {
+		ch := b.GB.Get(l1)
+		next := b.advance(col, ch)
+		if cur.indent+next-cur.col > w && l1 > cur.start {
+			if brk > cur.start {
+				l1, col = brk, brkCol
+			}
+			cur.end = l1
+			rows = append(rows, cur)
+			cur = row{start: l1, col: col, indent: indent}
+			brk = -1
+			ch = b.GB.Get(l1)
+			next = b.advance(col, ch)
+		}
+		if (ch == ' ' || ch == '\t') && l1 > text {
+			brk, brkCol = l1+1, next
+		}
+		col = next
+	}
+	cur.end = end
+	return append(rows, cur)
+}
+
+// rowAt returns which of rows pos is drawn on.
+func rowAt(rows []row, pos int) int {
+	for i, rw := range rows[:len(rows)-1] {
+		if pos < rw.end {
+			return i
+		}
+	}
+	return len(rows) - 1
+}
+
+// column returns the cell within rw at which pos is drawn.
+func (b *Buffer) column(rw row, pos int) int {
+	col := rw.col
+	for l1 := rw.start; l1 < pos; l1++ {
+		col = b.advance(col, b.GB.Get(l1))
+	}
+	return rw.indent + col - rw.col
+}
+
+// rowPos returns the position drawn at cell x of rw.
+func (b *Buffer) rowPos(rw row, x int, last bool) int {
+	col := rw.col
+	for l1 := rw.start; l1 < rw.end; l1++ {
+		if x <= rw.indent+col-rw.col {
+			return l1
+		}
+		col = b.advance(col, b.GB.Get(l1))
+	}
+	// The end of a wrapped row is the start of the next one, so stop
+	// short of it to stay on this row.
+	if !last && rw.end > rw.start {
+		return rw.end - 1
+	}
+	return rw.end
+}
+
+// visual returns the cell pos is drawn at in a text area w wide, counting
+// rows from the top of line Scroll and not allowing for XScroll.
+func (b *Buffer) visual(pos, w int) (x, y int) {
+	line := b.LineAt(pos)
+	rows := b.lineRows(line, w)
+	i := rowAt(rows, pos)
+	y = i
+	for l1 := b.Scroll; l1 < line; l1++ {
+		y += len(b.lineRows(l1, w))
+	}
+	for l1 := line; l1 < b.Scroll; l1++ {
+		y -= len(b.lineRows(l1, w))
+	}
+	return b.column(rows[i], pos), y
+}
+
+// ScreenPos returns where pos is drawn when the buffer is rendered into r.
+func (b *Buffer) ScreenPos(r core.Rect, pos int) (x, y int) {
+	r = b.textRect(r)
+	x, y = b.visual(pos, r.W)
+	return r.X + x - b.xScroll(), r.Y + y
+}
+
+// PosAt returns the position drawn at screen cell x, y when the buffer is
+// rendered into r. Cells in the gutter map to the start of their row.
+func (b *Buffer) PosAt(r core.Rect, x, y int) int {
+	r = b.textRect(r)
+	x += b.xScroll() - r.X
+	y -= r.Y
+	if y < 0 {
+		return b.GetPos(x, b.Scroll+y)
+	}
+	for l1 := b.Scroll; l1 < b.LineCount(); l1++ {
+		rows := b.lineRows(l1, r.W)
+		if y < len(rows) {
+			return b.rowPos(rows[y], x, y == len(rows)-1)
+		}
+		y -= len(rows)
+	}
+	return b.GB.Len()
+}
+
+func (b *Buffer) xScroll() int {
+	if b.Wrap {
+		return 0
+	}
+	return b.XScroll
+}
+
+// scrollTo adjusts Scroll and XScroll so the cursor is inside a text area
+// of size r.
+func (b *Buffer) scrollTo(r core.Rect) {
+	if b.Scroll > b.CurY {
+		b.Scroll = b.CurY
+	}
+	if !b.Wrap {
+		if b.Scroll < b.CurY-(r.H-1) {
+			b.Scroll = b.CurY - (r.H - 1)
+		}
+		// Follow the cursor sideways too, by where it is actually drawn
+		// rather than CurX, which may be past the end of a short line.
+		cx, _ := b.GetCur(b.Pos())
+		if b.XScroll > cx {
+			b.XScroll = cx
+		}
+		if b.XScroll < cx-(r.W-1) {
+			b.XScroll = cx - (r.W - 1)
+		}
+		if b.XScroll < 0 {
+			b.XScroll = 0
+		}
+		return
+	}
+	_, y := b.visual(b.Pos(), r.W)
+	for y > r.H-1 && b.Scroll < b.CurY {
+		y -= len(b.lineRows(b.Scroll, r.W))
+		b.Scroll++
+	}
+}
+
+// moveRows moves the cursor n screen rows up or down a text area of size
+// r, keeping to the same cell where the row is long enough.
+func (b *Buffer) moveRows(r core.Rect, n int) {
+	pos := b.Pos()
+	line := b.LineAt(pos)
+	rows := b.lineRows(line, r.W)
+	i := rowAt(rows, pos)
+	x := b.column(rows[i], pos)
+	for ; n < 0; n++ {
+		if i > 0 {
+			i--
+			continue
+		}
+		if line == 0 {
+			break
+		}
+		line--
+		rows = b.lineRows(line, r.W)
+		i = len(rows) - 1
+	}
+	for ; n > 0; n-- {
+		if i < len(rows)-1 {
+			i++
+			continue
+		}
+		if line >= b.Height() {
+			break
+		}
+		line++
+		rows = b.lineRows(line, r.W)
+		i = 0
+	}
+	b.SetPos(b.rowPos(rows[i], x, i == len(rows)-1))
+}
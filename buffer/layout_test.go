@@ -0,0 +1,23 @@
+package buffer
+
+import "testing"
+
+func TestLineRowsIndented(t *testing.T) {
+	// An indented line with a word too long to fit after its indentation.
+	b := New([]rune("        wraparound text here"))
+	b.Wrap = true
+	rows := b.lineRows(0, 12)
+	var got []string
+	for _, rw := range rows {
+		got = append(got, string(b.runes(rw.start, rw.end)))
+	}
+	want := []string{"        wrap", "around text ", "here"}
+	if len(got) != len(want) {
+		t.Fatalf("got rows %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d is %q, want %q", i, got[i], want[i])
+		}
+	}
+}
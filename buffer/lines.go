@@ -0,0 +1,172 @@
+package buffer
+
+import (
+	"sort"
+
+	"github.com/andyleap/gapbuffer"
+)
+
+// lineIndex holds the offset of the first rune of every line, kept up to
+// date as the buffer is edited so position lookups never rescan the text.
+//
+// Rather than rewrite every later start on each edit, the starts of lines
+// after stepLine are stored step short, and the step is only carried past
+// the lines between one edit and the next. Edits close together, as typing
+// is, then cost no more than the distance between them, whatever the size
+// of the buffer.
+type lineIndex struct {
+	starts   []int
+	stepLine int
+	step     int
+}
+
+func (li *lineIndex) build(gb *gapbuffer.GapBuffer) {
+	li.starts = append(li.starts[:0], 0)
+	li.stepLine, li.step = 0, 0
+	for l1 := 0; l1 < gb.Len(); l1++ {
+		if gb.Get(l1) == '\n' {
+			li.starts = append(li.starts, l1+1)
+		}
+	}
+}
+
+func (li *lineIndex) count() int {
+	return len(li.starts)
+}
+
+// start returns the offset of the first rune of line i.
+func (li *lineIndex) start(i int) int {
+	if i > li.stepLine {
+		return li.starts[i] + li.step
+	}
+	return li.starts[i]
+}
+
+// moveStep makes l the last line whose start is stored as it is.
+func (li *lineIndex) moveStep(l int) {
+	if li.step == 0 {
+		li.stepLine = l
+		return
+	}
+	for ; li.stepLine < l; li.stepLine++ {
+		li.starts[li.stepLine+1] += li.step
+	}
+	for ; li.stepLine > l; li.stepLine-- {
+		li.starts[li.stepLine] -= li.step
+	}
+}
+
+// line returns the line containing pos.
+func (li *lineIndex) line(pos int) int {
+	return sort.Search(len(li.starts), func(i int) bool { return li.start(i) > pos }) - 1
+}
+
+func (li *lineIndex) insert(pos int, text []rune) {
+	l := li.line(pos)
+	li.moveStep(l)
+	li.step += len(text)
+	var added []int
+	for i, ch := range text {
+		if ch == '\n' {
+			added = append(added, pos+i+1-li.step)
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+	li.starts = append(li.starts, added...)
+	copy(li.starts[l+1+len(added):], li.starts[l+1:])
+	copy(li.starts[l+1:], added)
+}
+
+func (li *lineIndex) delete(pos, n int) {
+	first := li.line(pos) + 1
+	li.moveStep(first - 1)
+	last := first
+	for last < len(li.starts) && li.start(last) <= pos+n {
+		last++
+	}
+	li.starts = append(li.starts[:first], li.starts[last:]...)
+	li.step -= n
+}
+
+// LineCount returns the number of lines in the buffer.
+func (b *Buffer) LineCount() int {
+	return b.lines.count()
+}
+
+// LineOffset returns the position of the first rune of line y.
+func (b *Buffer) LineOffset(y int) int {
+	if y < 0 {
+		return 0
+	}
+	if y >= b.lines.count() {
+		return b.GB.Len()
+	}
+	return b.lines.start(y)
+}
+
+// LineEnd returns the position of the newline ending line y, or the end of
+// the buffer for the last line.
+func (b *Buffer) LineEnd(y int) int {
+	if y+1 >= b.lines.count() {
+		return b.GB.Len()
+	}
+	if y < 0 {
+		return 0
+	}
+	return b.lines.start(y+1) - 1
+}
+
+// LineAt returns the line containing pos.
+func (b *Buffer) LineAt(pos int) int {
+	return b.lines.line(pos)
+}
+
+// advance returns the column after drawing ch at column x.
+func (b *Buffer) advance(x int, ch rune) int {
+	if ch == '\t' {
+		tw := b.tabWidth()
+		return x + tw - (x % tw)
+	}
+	return x + 1
+}
+
+// GetPos converts a screen column and line into a buffer position.
+func (b *Buffer) GetPos(x, y int) int {
+	if y < 0 {
+		return 0
+	}
+	if y >= b.lines.count() {
+		return b.GB.Len()
+	}
+	end := b.LineEnd(y)
+	xPos := 0
+	for l1 := b.lines.start(y); l1 < end; l1++ {
+		if x <= xPos {
+			return l1
+		}
+		xPos = b.advance(xPos, b.GB.Get(l1))
+	}
+	return end
+}
+
+// GetCur converts a buffer position into a screen column and line.
+func (b *Buffer) GetCur(pos int) (x, y int) {
+	if pos > b.GB.Len() {
+		pos = b.GB.Len()
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	y = b.lines.line(pos)
+	for l1 := b.lines.start(y); l1 < pos; l1++ {
+		x = b.advance(x, b.GB.Get(l1))
+	}
+	return x, y
+}
+
+// GotoLine moves the cursor to the start of line y.
+func (b *Buffer) GotoLine(y int) {
+	b.SetPos(b.LineOffset(y))
+}
@@ -0,0 +1,106 @@
+package buffer
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// checkLines compares b's line index with one built from scratch.
+func checkLines(t *testing.T, b *Buffer) {
+	t.Helper()
+	var want lineIndex
+	want.build(b.GB)
+	if b.lines.count() != want.count() {
+		t.Fatalf("%d lines, want %d", b.lines.count(), want.count())
+	}
+	for i := 0; i < want.count(); i++ {
+		if got := b.lines.start(i); got != want.start(i) {
+			t.Fatalf("line %d starts at %d, want %d", i, got, want.start(i))
+		}
+	}
+}
+
+func TestLineIndex(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	b := New([]rune("one\ntwo\n\nthree\nfour"))
+	pieces := []string{"x", "\n", "ab\ncd", "\n\n", "yz"}
+	for l1 := 0; l1 < 2000; l1++ {
+		pos := rnd.Intn(b.GB.Len() + 1)
+		if rnd.Intn(3) == 0 && b.GB.Len() > 0 {
+			n := rnd.Intn(6)
+			if pos+n > b.GB.Len() {
+				n = b.GB.Len() - pos
+			}
+			b.Remove(pos, n)
+		} else {
+			b.InsertAt(pos, []rune(pieces[rnd.Intn(len(pieces))]))
+		}
+		checkLines(t, b)
+		text := []rune(b.Text())
+		for l2 := 0; l2 < 5; l2++ {
+			pos := rnd.Intn(len(text) + 1)
+			if got, want := b.LineAt(pos), strings.Count(string(text[:pos]), "\n"); got != want {
+				t.Fatalf("LineAt(%d) = %d, want %d", pos, got, want)
+			}
+		}
+	}
+}
+
+// largeBuffer returns a buffer of about 20k lines of Go-like code.
+func largeBuffer() *Buffer {
+	var sb strings.Builder
+	for l1 := 0; l1 < 20000; l1++ {
+		sb.WriteString("\tif err := doSomething(ctx, x, y); err != nil {\n")
+	}
+	return New([]rune(sb.String()))
+}
+
+func BenchmarkGetPos(b *testing.B) {
+	buf := largeBuffer()
+	rnd := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for l1 := 0; l1 < b.N; l1++ {
+		buf.GetPos(rnd.Intn(60), rnd.Intn(buf.LineCount()))
+	}
+}
+
+func BenchmarkGetCur(b *testing.B) {
+	buf := largeBuffer()
+	rnd := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for l1 := 0; l1 < b.N; l1++ {
+		buf.GetCur(rnd.Intn(buf.GB.Len()))
+	}
+}
+
+// BenchmarkInsert types into the middle of the buffer, moving to another
+// line every so often as editing does.
+func BenchmarkInsert(b *testing.B) {
+	buf := largeBuffer()
+	pos := buf.LineOffset(buf.LineCount() / 2)
+	b.ResetTimer()
+	for l1 := 0; l1 < b.N; l1++ {
+		if l1%50 == 0 {
+			line := buf.LineAt(pos) + 1
+			if line >= buf.LineCount()-1 {
+				line = buf.LineCount() / 2
+			}
+			pos = buf.LineOffset(line) + 1
+		}
+		buf.InsertAt(pos, []rune{'x'})
+		pos++
+	}
+}
+
+// BenchmarkInsertNewline splits lines in the middle of the buffer, which
+// moves the starts of the lines after it along.
+func BenchmarkInsertNewline(b *testing.B) {
+	buf := largeBuffer()
+	pos := buf.LineOffset(buf.LineCount() / 2)
+	b.ResetTimer()
+	for l1 := 0; l1 < b.N; l1++ {
+		buf.InsertAt(pos, []rune{'\n'})
+		pos++
+	}
+}
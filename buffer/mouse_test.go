@@ -0,0 +1,67 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+func click(b *Buffer, key termbox.Key, mod termbox.Modifier, x, y int) {
+	r := core.Rect{W: 20, H: 5}
+	b.Handle(r, termbox.Event{Type: termbox.EventMouse, Key: key, Mod: mod, MouseX: x, MouseY: y})
+}
+
+func TestRightClick(t *testing.T) {
+	for _, test := range []struct {
+		filename string
+		clicks   int
+	}{
+		{"main.go", 1},
+		{"README.md", 0},
+		{"", 0},
+	} {
+		b := New([]rune("one\ntwo\n"))
+		b.Filename = test.filename
+		clicks := 0
+		b.OnRightClick = func() { clicks++ }
+		click(b, termbox.MouseRight, 0, 1, 1)
+		if clicks != test.clicks {
+			t.Errorf("%q: right click called OnRightClick %d times, want %d", test.filename, clicks, test.clicks)
+		}
+		if b.Pos() != 5 {
+			t.Errorf("%q: cursor at %d, want 5 where the click was", test.filename, b.Pos())
+		}
+	}
+}
+
+func TestCtrlClick(t *testing.T) {
+	b := New([]rune("one\ntwo\n"))
+	clicks := 0
+	b.OnCtrlClick = func() { clicks++ }
+
+	click(b, termbox.MouseRight, 0, 1, 1)
+	click(b, termbox.MouseLeft, 0, 1, 1)
+	if clicks != 0 {
+		t.Errorf("plain clicks called OnCtrlClick %d times", clicks)
+	}
+	b.SetPos(0)
+	click(b, termbox.MouseLeft, termbox.ModCtrl, 1, 1)
+	if clicks != 1 {
+		t.Errorf("Ctrl+click called OnCtrlClick %d times, want 1", clicks)
+	}
+	if b.Pos() != 5 {
+		t.Errorf("cursor at %d, want 5 where the click was", b.Pos())
+	}
+}
+
+func TestDragWithModifier(t *testing.T) {
+	for _, mod := range []termbox.Modifier{0, termbox.ModCtrl, termbox.ModShift} {
+		b := New([]rune("one\ntwo\n"))
+		click(b, termbox.MouseLeft, 0, 0, 0)
+		click(b, termbox.MouseLeft, mod|termbox.ModMotion, 2, 1)
+		if b.Pos() != 0 || b.Sel != 6 {
+			t.Errorf("mod %d: dragging left %d-%d selected, want 0-6", mod, b.Pos(), b.Sel)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/andyleap/editor/core/screentest"
+)
+
+const renderText = "package main\n\nfunc main() {\n\tfmt.Println(\"hello, world\")\n}\n"
+
+func TestRender(t *testing.T) {
+	b := New([]rune(renderText))
+	b.SetPos(b.LineOffset(3) + 3)
+	m := screentest.Render(b, 40, 8)
+	screentest.Golden(t, "render", m.String()+"\n"+screentest.Highlights(m))
+}
+
+func TestRenderGutter(t *testing.T) {
+	b := New([]rune(renderText))
+	b.Gutter = true
+	b.SetPos(b.LineOffset(2))
+	m := screentest.Render(b, 40, 8)
+	screentest.Golden(t, "render_gutter", m.String())
+}
+
+func TestRenderWrap(t *testing.T) {
+	b := New([]rune(renderText))
+	b.Wrap = true
+	b.WrapIndent = true
+	m := screentest.Render(b, 20, 8)
+	screentest.Golden(t, "render_wrap", m.String())
+}
+
+func TestRenderSelection(t *testing.T) {
+	b := New([]rune(renderText))
+	b.Sel = b.LineOffset(2) + 5
+	b.SetPos(b.LineOffset(3) + 4)
+	m := screentest.Render(b, 40, 8)
+	screentest.Golden(t, "render_selection", m.String()+"\n"+screentest.Highlights(m))
+}
+
+func TestRenderCursors(t *testing.T) {
+	b := New([]rune(renderText))
+	b.SetPos(b.LineOffset(2) + 5)
+	b.AddNextOccurrence()
+	b.AddNextOccurrence()
+	m := screentest.Render(b, 40, 8)
+	screentest.Golden(t, "render_cursors", m.String()+"\n"+screentest.Highlights(m))
+}
+
+func TestRenderBlock(t *testing.T) {
+	b := New([]rune(renderText))
+	b.SetPos(2)
+	b.startBlock()
+	b.CurX, b.CurY = 6, 3
+	m := screentest.Render(b, 40, 8)
+	screentest.Golden(t, "render_block", m.String()+"\n"+screentest.Highlights(m))
+}
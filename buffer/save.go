@@ -0,0 +1,87 @@
+package buffer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoFilename is returned when saving a buffer that has never been given
+// a file name.
+var ErrNoFilename = errors.New("buffer has no file name")
+
+// BackupSuffix is added to a file's name to name its backup.
+const BackupSuffix = "~"
+
+// resolve follows filename through any symlinks, so that saving replaces
+// the file they point at rather than the links themselves.
+func resolve(filename string) (string, error) {
+	target, err := filepath.EvalSymlinks(filename)
+	if err == nil {
+		return target, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	// A dangling link is saved through, creating its target.
+	link, lerr := os.Readlink(filename)
+	if lerr != nil {
+		return filename, nil
+	}
+	if !filepath.IsAbs(link) {
+		link = filepath.Join(filepath.Dir(filename), link)
+	}
+	return resolve(link)
+}
+
+// writeFile replaces filename with data without ever leaving it half
+// written: the data goes to a temporary file in the same directory, which
+// is then renamed over the original. The original's permissions are kept
+// and, if backup is set, its old contents are first copied aside.
+func writeFile(filename string, data []byte, backup bool) (err error) {
+	target, err := resolve(filename)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(target); err == nil {
+		mode = fi.Mode().Perm()
+		if backup {
+			old, err := ioutil.ReadFile(target)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(target+BackupSuffix, old, mode); err != nil {
+				return err
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(target), "."+filepath.Base(target)+".")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,118 @@
+package buffer
+
+import (
+	"unicode"
+
+	"github.com/andyleap/termbox-go"
+)
+
+// Selection returns the ordered bounds of the text between Sel and the
+// cursor, and whether anything is selected.
+func (b *Buffer) Selection() (start, end int, ok bool) {
+	if b.Sel < 0 {
+		return 0, 0, false
+	}
+	start, end = b.Sel, b.Pos()
+	if start > end {
+		start, end = end, start
+	}
+	return start, end, start != end
+}
+
+// spans is a set of [start, end) ranges of the buffer.
+type spans [][2]int
+
+func (s spans) contain(pos int) bool {
+	for _, sp := range s {
+		if pos >= sp[0] && pos < sp[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// selections returns what is selected at every cursor, or on every line of
+// a block selection.
+func (b *Buffer) selections() spans {
+	var s spans
+	cursors := b.blockCursors()
+	if !b.Block {
+		cursors = append([]Cursor{{Pos: b.Pos(), Sel: b.Sel}}, b.Cursors...)
+	}
+	for _, c := range cursors {
+		if c.Sel < 0 || c.Sel == c.Pos {
+			continue
+		}
+		start, end := c.Sel, c.Pos
+		if start > end {
+			start, end = end, start
+		}
+		s = append(s, [2]int{start, end})
+	}
+	return s
+}
+
+// SelectedText returns the runes between Sel and the cursor.
+func (b *Buffer) SelectedText() []rune {
+	start, end, ok := b.Selection()
+	if !ok {
+		return nil
+	}
+	text := make([]rune, 0, end-start)
+	for l1 := start; l1 < end; l1++ {
+		text = append(text, b.GB.Get(l1))
+	}
+	return text
+}
+
+// DeleteSelection removes the selected text, leaving the cursor where it
+// began. It reports whether anything was removed.
+func (b *Buffer) DeleteSelection() bool {
+	start, end, ok := b.Selection()
+	b.Sel = -1
+	if !ok {
+		return false
+	}
+	b.Remove(start, end-start)
+	b.SetPos(start)
+	return true
+}
+
+// motion prepares for a cursor movement: with shift held the selection is
+// anchored at the current position (if it isn't already), otherwise it is
+// dropped.
+func (b *Buffer) motion(evt termbox.Event) {
+	if evt.Mod&termbox.ModShift != 0 {
+		if b.Sel < 0 {
+			b.Sel = b.Pos()
+		}
+		return
+	}
+	b.Sel = -1
+}
+
+func isWordChar(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch) || unicode.IsDigit(ch)
+}
+
+// WordLeft returns the start of the word before pos.
+func (b *Buffer) WordLeft(pos int) int {
+	for pos > 0 && !isWordChar(b.GB.Get(pos-1)) {
+		pos--
+	}
+	for pos > 0 && isWordChar(b.GB.Get(pos-1)) {
+		pos--
+	}
+	return pos
+}
+
+// WordRight returns the end of the word after pos.
+func (b *Buffer) WordRight(pos int) int {
+	for pos < b.GB.Len() && !isWordChar(b.GB.Get(pos)) {
+		pos++
+	}
+	for pos < b.GB.Len() && isWordChar(b.GB.Get(pos)) {
+		pos++
+	}
+	return pos
+}
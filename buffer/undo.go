@@ -0,0 +1,202 @@
+package buffer
+
+const maxUndo = 1000
+
+// edit is a single reversible change to the buffer contents: either text
+// inserted at pos, or text removed from pos.
+type edit struct {
+	pos    int
+	text   []rune
+	insert bool
+}
+
+// undoStep groups the edits made by one user action, along with the cursor
+// and selection on either side of it so they can be restored.
+type undoStep struct {
+	edits []edit
+
+	beforePos, beforeSel int
+	afterPos, afterSel   int
+
+	typing bool
+}
+
+type history struct {
+	undo []*undoStep
+	redo []*undoStep
+
+	cur   *undoStep
+	depth int
+
+	clean *undoStep
+}
+
+func (h *history) top() *undoStep {
+	if len(h.undo) == 0 {
+		return nil
+	}
+	return h.undo[len(h.undo)-1]
+}
+
+// BeginEdit opens an undo step. Every mutation until the matching EndEdit is
+// undone and redone as a single unit. Calls may be nested.
+func (b *Buffer) BeginEdit() {
+	b.hist.depth++
+	if b.hist.depth > 1 {
+		return
+	}
+	b.hist.cur = &undoStep{
+		beforePos: b.Pos(),
+		beforeSel: b.Sel,
+	}
+}
+
+// EndEdit closes the undo step opened by BeginEdit.
+func (b *Buffer) EndEdit() {
+	if b.hist.depth == 0 {
+		return
+	}
+	b.hist.depth--
+	if b.hist.depth > 0 {
+		return
+	}
+	step := b.hist.cur
+	b.hist.cur = nil
+	if len(step.edits) == 0 {
+		return
+	}
+	step.afterPos, step.afterSel = b.Pos(), b.Sel
+	// Anything undone can no longer be redone once something else has
+	// been changed, whether or not this step is merged into the last.
+	b.hist.redo = b.hist.redo[:0]
+
+	if len(step.edits) == 1 {
+		e := step.edits[0]
+		step.typing = e.insert && len(e.text) == 1 && e.text[0] != '\n'
+	}
+	if prev := b.hist.top(); step.typing && prev != nil && prev.typing && prev != b.hist.clean {
+		last := prev.edits[len(prev.edits)-1]
+		if prev.afterPos == step.beforePos && last.pos+len(last.text) == step.edits[0].pos {
+			prev.edits = append(prev.edits, step.edits[0])
+			prev.afterPos, prev.afterSel = step.afterPos, step.afterSel
+			return
+		}
+	}
+
+	b.hist.undo = append(b.hist.undo, step)
+	if len(b.hist.undo) > maxUndo {
+		b.hist.undo = b.hist.undo[len(b.hist.undo)-maxUndo:]
+	}
+}
+
+func (b *Buffer) record(e edit) {
+	if b.hist.cur == nil {
+		return
+	}
+	b.hist.cur.edits = append(b.hist.cur.edits, e)
+}
+
+// Undo reverts the most recent undo step.
+func (b *Buffer) Undo() bool {
+	if b.hist.depth > 0 || len(b.hist.undo) == 0 {
+		return false
+	}
+	step := b.hist.undo[len(b.hist.undo)-1]
+	b.hist.undo = b.hist.undo[:len(b.hist.undo)-1]
+	for l1 := len(step.edits) - 1; l1 >= 0; l1-- {
+		e := step.edits[l1]
+		if e.insert {
+			b.rawDelete(e.pos, len(e.text))
+		} else {
+			b.rawInsert(e.pos, e.text)
+		}
+	}
+	b.hist.redo = append(b.hist.redo, step)
+	b.SetPos(step.beforePos)
+	b.Sel = step.beforeSel
+	b.Dirty = b.hist.top() != b.hist.clean
+	return true
+}
+
+// Redo reapplies the most recently undone step.
+func (b *Buffer) Redo() bool {
+	if b.hist.depth > 0 || len(b.hist.redo) == 0 {
+		return false
+	}
+	step := b.hist.redo[len(b.hist.redo)-1]
+	b.hist.redo = b.hist.redo[:len(b.hist.redo)-1]
+	for _, e := range step.edits {
+		if e.insert {
+			b.rawInsert(e.pos, e.text)
+		} else {
+			b.rawDelete(e.pos, len(e.text))
+		}
+	}
+	b.hist.undo = append(b.hist.undo, step)
+	b.SetPos(step.afterPos)
+	b.Sel = step.afterSel
+	b.Dirty = b.hist.top() != b.hist.clean
+	return true
+}
+
+func (b *Buffer) resetHistory() {
+	b.hist = history{}
+}
+
+func (b *Buffer) markClean() {
+	b.hist.clean = b.hist.top()
+	b.Dirty = false
+}
+
+func (b *Buffer) rawInsert(pos int, text []rune) {
+	b.lines.insert(pos, text)
+	b.rev++
+	b.shiftCursors(pos, len(text))
+	b.shiftParked(pos, len(text))
+	for i, ch := range text {
+		b.GB.Insert(pos+i, ch)
+		for _, s := range b.stylers {
+			s.Insert(pos + i)
+		}
+	}
+}
+
+func (b *Buffer) rawDelete(pos, n int) []rune {
+	text := []rune(b.GB.Cut(pos, n))
+	b.lines.delete(pos, len(text))
+	b.rev++
+	b.shiftCursors(pos, -len(text))
+	b.shiftParked(pos, -len(text))
+	for range text {
+		for _, s := range b.stylers {
+			s.Delete(pos + 1)
+		}
+	}
+	return text
+}
+
+// InsertAt inserts text at pos without moving the cursor.
+func (b *Buffer) InsertAt(pos int, text []rune) {
+	if len(text) == 0 {
+		return
+	}
+	b.BeginEdit()
+	defer b.EndEdit()
+	b.rawInsert(pos, text)
+	b.record(edit{pos: pos, text: append([]rune(nil), text...), insert: true})
+	b.Dirty = true
+}
+
+// Remove deletes n runes starting at pos and returns them, without moving
+// the cursor.
+func (b *Buffer) Remove(pos, n int) []rune {
+	if n <= 0 {
+		return nil
+	}
+	b.BeginEdit()
+	defer b.EndEdit()
+	text := b.rawDelete(pos, n)
+	b.record(edit{pos: pos, text: text})
+	b.Dirty = true
+	return append([]rune(nil), text...)
+}
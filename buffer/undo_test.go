@@ -0,0 +1,55 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+func typeKeys(b *Buffer, keys ...interface{}) {
+	r := core.Rect{W: 80, H: 25}
+	for _, k := range keys {
+		switch k := k.(type) {
+		case string:
+			for _, ch := range k {
+				b.Handle(r, termbox.Event{Type: termbox.EventKey, Ch: ch})
+			}
+		case termbox.Key:
+			b.Handle(r, termbox.Event{Type: termbox.EventKey, Key: k})
+		}
+	}
+}
+
+func TestUndoTyping(t *testing.T) {
+	b := New(nil)
+	typeKeys(b, "abc", termbox.KeyEnter, "de")
+	if !b.Undo() {
+		t.Fatal("nothing to undo")
+	}
+	if got := b.Text(); got != "abc\n" {
+		t.Errorf("after undo got %q, want %q", got, "abc\n")
+	}
+	b.Undo()
+	b.Undo()
+	if got := b.Text(); got != "" {
+		t.Errorf("after undoing everything got %q", got)
+	}
+	b.Redo()
+	if got := b.Text(); got != "abc" {
+		t.Errorf("after redo got %q, want %q", got, "abc")
+	}
+}
+
+func TestEditClearsRedo(t *testing.T) {
+	b := New(nil)
+	typeKeys(b, "abc", termbox.KeyEnter)
+	b.Undo()
+	typeKeys(b, "d")
+	if b.Redo() {
+		t.Error("redo still available after a new edit")
+	}
+	if got := b.Text(); got != "abcd" {
+		t.Errorf("got %q, want %q", got, "abcd")
+	}
+}
@@ -0,0 +1,193 @@
+package buffer
+
+import (
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+// State is the part of a Buffer that belongs to whoever is looking at it
+// rather than to the text: cursor, selection and scroll.
+type State struct {
+	CurX, CurY int
+	Sel        int
+	Cursors    []Cursor
+	Block      bool
+	BlockX     int
+	BlockY     int
+	Scroll     int
+	XScroll    int
+}
+
+func (b *Buffer) State() State {
+	return State{
+		CurX:    b.CurX,
+		CurY:    b.CurY,
+		Sel:     b.Sel,
+		Cursors: append([]Cursor(nil), b.Cursors...),
+		Block:   b.Block,
+		BlockX:  b.BlockX,
+		BlockY:  b.BlockY,
+		Scroll:  b.Scroll,
+		XScroll: b.XScroll,
+	}
+}
+
+// SetState restores a State, clamping it to the current contents since the
+// text may have changed since it was taken.
+func (b *Buffer) SetState(s State) {
+	b.CurX, b.CurY = s.CurX, s.CurY
+	if b.CurY > b.Height() {
+		b.CurY = b.Height()
+	}
+	b.Sel = s.Sel
+	if b.Sel > b.GB.Len() {
+		b.Sel = b.GB.Len()
+	}
+	b.Cursors = b.Cursors[:0]
+	for _, c := range s.Cursors {
+		if c.Pos > b.GB.Len() {
+			c.Pos = b.GB.Len()
+		}
+		if c.Sel > b.GB.Len() {
+			c.Sel = b.GB.Len()
+		}
+		b.Cursors = append(b.Cursors, c)
+	}
+	b.mergeCursors()
+	b.Block, b.BlockX, b.BlockY = s.Block, s.BlockX, s.BlockY
+	if b.BlockY > b.Height() {
+		b.BlockY = b.Height()
+	}
+	b.Scroll = s.Scroll
+	if b.Scroll > b.CurY {
+		b.Scroll = b.CurY
+	}
+	b.XScroll = s.XScroll
+}
+
+// parked is the State of a view that isn't driving the buffer, with its
+// line-based parts also held as offsets so they can follow edits made
+// through another view.
+type parked struct {
+	state *State
+	pos   int
+	moved bool
+	// scroll and block are the starts of the first line shown and of the
+	// line the block selection is anchored on.
+	scroll, block int
+}
+
+// park has s follow edits to the buffer until it is unparked.
+func (b *Buffer) park(s *State) {
+	b.parked = append(b.parked, &parked{
+		state:  s,
+		pos:    b.GetPos(s.CurX, s.CurY),
+		scroll: b.LineOffset(s.Scroll),
+		block:  b.LineOffset(s.BlockY),
+	})
+}
+
+// unpark stops s following edits, bringing its lines up to date.
+func (b *Buffer) unpark(s *State) {
+	for i, p := range b.parked {
+		if p.state != s {
+			continue
+		}
+		if p.moved {
+			s.CurX, s.CurY = b.GetCur(p.pos)
+		}
+		s.Scroll = b.LineAt(p.scroll)
+		s.BlockY = b.LineAt(p.block)
+		b.parked = append(b.parked[:i], b.parked[i+1:]...)
+		return
+	}
+}
+
+// shiftParked moves the parked states along with n runes inserted at pos,
+// or removed from it when n is negative.
+func (b *Buffer) shiftParked(pos, n int) {
+	for _, p := range b.parked {
+		if moved := shiftPos(p.pos, pos, n); moved != p.pos {
+			p.pos, p.moved = moved, true
+		}
+		p.scroll = shiftPos(p.scroll, pos, n)
+		p.block = shiftPos(p.block, pos, n)
+		if p.state.Sel >= 0 {
+			p.state.Sel = shiftPos(p.state.Sel, pos, n)
+		}
+		for i, c := range p.state.Cursors {
+			p.state.Cursors[i].Pos = shiftPos(c.Pos, pos, n)
+			if c.Sel >= 0 {
+				p.state.Cursors[i].Sel = shiftPos(c.Sel, pos, n)
+			}
+		}
+	}
+}
+
+// View displays a Buffer, along with any UI layered over it, with its own
+// State, so the same buffer can be shown in several panes at once. The
+// focused view drives the buffer's own state directly; the others swap
+// theirs in only while rendering or handling an event.
+type View struct {
+	Buf *Buffer
+	UI  core.UI
+
+	state    State
+	inactive bool
+}
+
+func NewView(b *Buffer, ui core.UI) *View {
+	return &View{
+		Buf:   b,
+		UI:    ui,
+		state: b.State(),
+	}
+}
+
+func (v *View) SetFocus(focused bool) {
+	if focused == !v.inactive {
+		return
+	}
+	if focused {
+		v.Buf.unpark(&v.state)
+		v.Buf.SetState(v.state)
+	} else {
+		v.state = v.Buf.State()
+		v.Buf.park(&v.state)
+	}
+	v.inactive = !focused
+}
+
+// Close lets go of the view's state, which otherwise stays with the buffer
+// to follow its edits. The view can't be used again.
+func (v *View) Close() {
+	if v.inactive {
+		v.Buf.unpark(&v.state)
+	}
+}
+
+func (v *View) swap() func() {
+	if !v.inactive {
+		return func() {}
+	}
+	saved := v.Buf.State()
+	v.Buf.park(&saved)
+	v.Buf.unpark(&v.state)
+	v.Buf.SetState(v.state)
+	return func() {
+		v.state = v.Buf.State()
+		v.Buf.park(&v.state)
+		v.Buf.unpark(&saved)
+		v.Buf.SetState(saved)
+	}
+}
+
+func (v *View) Render(r core.Rect) {
+	defer v.swap()()
+	v.UI.Render(r)
+}
+
+func (v *View) Handle(r core.Rect, evt termbox.Event) bool {
+	defer v.swap()()
+	return v.UI.Handle(r, evt)
+}
@@ -0,0 +1,51 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/andyleap/editor/core"
+)
+
+func TestViewFollowsEdits(t *testing.T) {
+	b := New([]rune("one\ntwo\nthree\nfour\n"))
+	v1 := NewView(b, b)
+	v2 := NewView(b, b)
+	v1.SetFocus(false)
+
+	// The second view is scrolled to "three", with its cursor in "four".
+	b.CurX, b.CurY = 1, 3
+	b.Scroll = 2
+	v2.SetFocus(false)
+	v1.SetFocus(true)
+
+	b.SetPos(0)
+	b.InsertString("zero\n")
+	b.SetPos(b.LineOffset(2))
+	b.Remove(b.Pos(), 4)
+
+	v1.SetFocus(false)
+	v2.SetFocus(true)
+	if b.CurY != 3 || b.CurX != 1 {
+		t.Errorf("cursor at %d,%d, want 1,3", b.CurX, b.CurY)
+	}
+	if got := b.GB.Get(b.Pos()); got != 'o' {
+		t.Errorf("cursor on %q, want 'o' of \"four\"", got)
+	}
+	if b.Scroll != 2 {
+		t.Errorf("scrolled to line %d, want 2", b.Scroll)
+	}
+}
+
+func TestClosedViewsLetGo(t *testing.T) {
+	b := New([]rune("one\ntwo\n"))
+	var main core.UI = NewView(b, b)
+	before := len(b.parked)
+	for l1 := 0; l1 < 5; l1++ {
+		split := core.NewHSplit(main, NewView(b, b))
+		split.FocusOn(l1 % 2)
+		main = split.CloseFocused()
+	}
+	if len(b.parked) != before {
+		t.Errorf("%d states parked after closing the panes, want %d", len(b.parked), before)
+	}
+}
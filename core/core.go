@@ -2,13 +2,14 @@ package core
 
 import (
 	"log"
+	"sync"
 
 	"github.com/andyleap/termbox-go"
 )
 
 func RenderString(x, y int, text string, fg, bg termbox.Attribute) {
 	for p, c := range text {
-		termbox.SetCell(x+p, y, c, fg, bg)
+		SetCell(x+p, y, c, fg, bg)
 	}
 }
 
@@ -17,20 +18,20 @@ func RenderString(x, y int, text string, fg, bg termbox.Attribute) {
 
 func Frame(r Rect, fg, bg termbox.Attribute) {
 	for l1 := r.X + 1; l1 < r.X+r.W-1; l1++ {
-		termbox.SetCell(l1, r.Y, '─', fg, bg)
-		termbox.SetCell(l1, r.Y+r.H-1, '─', fg, bg)
+		SetCell(l1, r.Y, '─', fg, bg)
+		SetCell(l1, r.Y+r.H-1, '─', fg, bg)
 	}
 	for l1 := r.Y + 1; l1 < r.Y+r.H-1; l1++ {
-		termbox.SetCell(r.X, l1, '│', fg, bg)
-		termbox.SetCell(r.X+r.W-1, l1, '│', fg, bg)
+		SetCell(r.X, l1, '│', fg, bg)
+		SetCell(r.X+r.W-1, l1, '│', fg, bg)
 	}
-	termbox.SetCell(r.X, r.Y, '┌', fg, bg)
-	termbox.SetCell(r.X+r.W-1, r.Y, '┐', fg, bg)
-	termbox.SetCell(r.X, r.Y+r.H-1, '└', fg, bg)
-	termbox.SetCell(r.X+r.W-1, r.Y+r.H-1, '┘', fg, bg)
+	SetCell(r.X, r.Y, '┌', fg, bg)
+	SetCell(r.X+r.W-1, r.Y, '┐', fg, bg)
+	SetCell(r.X, r.Y+r.H-1, '└', fg, bg)
+	SetCell(r.X+r.W-1, r.Y+r.H-1, '┘', fg, bg)
 	for l1 := r.Y + 1; l1 < r.Y+r.H-1; l1++ {
 		for l2 := r.X + 1; l2 < r.X+r.W-1; l2++ {
-			termbox.SetCell(l2, l1, ' ', fg, bg)
+			SetCell(l2, l1, ' ', fg, bg)
 		}
 	}
 }
@@ -38,7 +39,7 @@ func Frame(r Rect, fg, bg termbox.Attribute) {
 func FrameBorderless(r Rect, fg, bg termbox.Attribute) {
 	for l1 := r.Y; l1 < r.Y+r.H; l1++ {
 		for l2 := r.X; l2 < r.X+r.W; l2++ {
-			termbox.SetCell(l2, l1, ' ', fg, bg)
+			SetCell(l2, l1, ' ', fg, bg)
 		}
 	}
 }
@@ -69,8 +70,17 @@ type UI interface {
 }
 
 type Core struct {
-	s Stack
+	s   Stack
 	Log *log.Logger
+
+	once   sync.Once
+	posted chan func()
+}
+
+func (c *Core) init() {
+	c.once.Do(func() {
+		c.posted = make(chan func(), 64)
+	})
 }
 
 func (c *Core) Add(ui UI) {
@@ -81,24 +91,70 @@ func (c *Core) Remove(ui UI) {
 	c.s.Remove(ui)
 }
 
-func (c *Core) Run() {
+// PostFunc is the type of Core.Post. Components that do slow work take one
+// to do it on a goroutine of its own and hand the results back; left nil,
+// they do the work in the call instead.
+type PostFunc func(fn func())
+
+// Post queues fn to be run on the UI goroutine, after which the screen is
+// redrawn. It is safe to call from any goroutine, and is how background
+// work hands its results back to the UI.
+func (c *Core) Post(fn func()) {
+	c.init()
+	select {
+	case c.posted <- fn:
+	default:
+		go func() { c.posted <- fn }()
+	}
+}
 
-	for {
-		termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+// Redraw asks for the screen to be redrawn from any goroutine.
+func (c *Core) Redraw() {
+	c.Post(nil)
+}
 
-		r := Rect{}
-		r.W, r.H = termbox.Size()
+// Draw renders one frame to the Current screen and returns the area it
+// covered.
+func (c *Core) Draw() Rect {
+	Current.Clear(termbox.ColorDefault, termbox.ColorDefault)
 
-		c.s.Render(r)
+	r := Rect{}
+	r.W, r.H = Current.Size()
 
-		termbox.Flush()
+	c.s.Render(r)
 
-		evt := termbox.PollEvent()
+	Current.Flush()
+	return r
+}
+
+// Handle passes an event to the UIs, topmost first.
+func (c *Core) Handle(r Rect, evt termbox.Event) bool {
+	if c.Log != nil {
+		c.Log.Printf("%#v", evt)
+	}
+	return c.s.Handle(r, evt)
+}
 
-		if c.Log != nil {
-			c.Log.Printf("%#v", evt)
+func (c *Core) Run() {
+	c.init()
+
+	events := make(chan termbox.Event)
+	go func() {
+		for {
+			events <- Current.PollEvent()
 		}
+	}()
 
-		c.s.Handle(r, evt)
+	for {
+		r := c.Draw()
+
+		select {
+		case evt := <-events:
+			c.Handle(r, evt)
+		case fn := <-c.posted:
+			if fn != nil {
+				fn()
+			}
+		}
 	}
 }
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/andyleap/termbox-go"
+)
+
+// Screen is the surface UIs draw on and the source of the events they
+// handle.
+type Screen interface {
+	SetCell(x, y int, ch rune, fg, bg termbox.Attribute)
+	SetCursor(x, y int)
+	HideCursor()
+	Size() (w, h int)
+	Clear(fg, bg termbox.Attribute)
+	Flush()
+	PollEvent() termbox.Event
+}
+
+// Current is the Screen that SetCell, SetCursor and HideCursor draw on.
+var Current Screen = Termbox{}
+
+func SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	Current.SetCell(x, y, ch, fg, bg)
+}
+
+func SetCursor(x, y int) {
+	Current.SetCursor(x, y)
+}
+
+func HideCursor() {
+	Current.HideCursor()
+}
+
+// Termbox is the Screen backed by the real terminal.
+type Termbox struct{}
+
+func (Termbox) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	termbox.SetCell(x, y, ch, fg, bg)
+}
+
+func (Termbox) SetCursor(x, y int)             { termbox.SetCursor(x, y) }
+func (Termbox) HideCursor()                    { termbox.HideCursor() }
+func (Termbox) Size() (w, h int)               { return termbox.Size() }
+func (Termbox) Clear(fg, bg termbox.Attribute) { termbox.Clear(fg, bg) }
+func (Termbox) Flush()                         { termbox.Flush() }
+func (Termbox) PollEvent() termbox.Event       { return termbox.PollEvent() }
+
+// MemScreen is an in-memory Screen that records a grid of cells, so what
+// UIs draw can be inspected without a terminal.
+type MemScreen struct {
+	W, H             int
+	Cells            []termbox.Cell
+	CursorX, CursorY int
+
+	Events chan termbox.Event
+}
+
+func NewMemScreen(w, h int) *MemScreen {
+	m := &MemScreen{
+		W:      w,
+		H:      h,
+		Cells:  make([]termbox.Cell, w*h),
+		Events: make(chan termbox.Event, 16),
+	}
+	m.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	m.HideCursor()
+	return m
+}
+
+func (m *MemScreen) SetCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	if x < 0 || x >= m.W || y < 0 || y >= m.H {
+		return
+	}
+	m.Cells[y*m.W+x] = termbox.Cell{Ch: ch, Fg: fg, Bg: bg}
+}
+
+// Cell returns the cell at x, y.
+func (m *MemScreen) Cell(x, y int) termbox.Cell {
+	if x < 0 || x >= m.W || y < 0 || y >= m.H {
+		return termbox.Cell{}
+	}
+	return m.Cells[y*m.W+x]
+}
+
+func (m *MemScreen) SetCursor(x, y int) {
+	m.CursorX, m.CursorY = x, y
+}
+
+func (m *MemScreen) HideCursor() {
+	m.CursorX, m.CursorY = -1, -1
+}
+
+func (m *MemScreen) Size() (w, h int) {
+	return m.W, m.H
+}
+
+func (m *MemScreen) Clear(fg, bg termbox.Attribute) {
+	for i := range m.Cells {
+		m.Cells[i] = termbox.Cell{Ch: ' ', Fg: fg, Bg: bg}
+	}
+}
+
+func (m *MemScreen) Flush() {}
+
+// PollEvent returns the next event queued with Send.
+func (m *MemScreen) PollEvent() termbox.Event {
+	return <-m.Events
+}
+
+// Send queues an event for PollEvent.
+func (m *MemScreen) Send(evt termbox.Event) {
+	m.Events <- evt
+}
+
+// String returns the characters on screen, one line per row, with trailing
+// spaces trimmed.
+func (m *MemScreen) String() string {
+	var sb strings.Builder
+	for y := 0; y < m.H; y++ {
+		row := make([]rune, m.W)
+		for x := 0; x < m.W; x++ {
+			row[x] = m.Cells[y*m.W+x].Ch
+		}
+		sb.WriteString(strings.TrimRight(string(row), " "))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
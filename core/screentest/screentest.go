@@ -0,0 +1,75 @@
+// Package screentest draws UIs into a core.MemScreen and compares what
+// they drew with golden files kept in testdata.
+package screentest
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with what was drawn")
+
+// Render draws ui into a w by h MemScreen, made the current screen while it
+// does so, and returns the screen.
+func Render(ui core.UI, w, h int) *core.MemScreen {
+	m := core.NewMemScreen(w, h)
+	saved := core.Current
+	core.Current = m
+	defer func() { core.Current = saved }()
+	ui.Render(core.Rect{W: w, H: h})
+	return m
+}
+
+// Highlights returns a map of m, a line per row, with '#' for each cell
+// drawn with a background colour or reversed, '_' for each one that is
+// underlined, and '.' for the rest. The cursor, if shown, is '|'.
+func Highlights(m *core.MemScreen) string {
+	var sb strings.Builder
+	for y := 0; y < m.H; y++ {
+		for x := 0; x < m.W; x++ {
+			c := m.Cell(x, y)
+			switch {
+			case x == m.CursorX && y == m.CursorY:
+				sb.WriteByte('|')
+			case c.Bg&0x1FF != termbox.ColorDefault || c.Fg&termbox.AttrReverse != 0:
+				sb.WriteByte('#')
+			case c.Fg&termbox.AttrUnderline != 0:
+				sb.WriteByte('_')
+			default:
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Golden compares got with testdata/name.golden, failing t if they differ.
+// Run the tests with -update to write got to the file instead.
+func Golden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from what was drawn:\n--- want\n%s--- got\n%s", path, want, got)
+	}
+}
@@ -0,0 +1,282 @@
+package core
+
+import "github.com/andyleap/termbox-go"
+
+// Focusable is implemented by UIs that need to know when a container moves
+// keyboard focus onto or away from them.
+type Focusable interface {
+	SetFocus(focused bool)
+}
+
+// Closer is implemented by UIs that hold on to something that must be let
+// go of when a container removes them for good.
+type Closer interface {
+	Close()
+}
+
+// Split divides its area between several UIs separated by a one cell
+// divider, side by side or, if Vertical is set, stacked top to bottom.
+// Keyboard events only reach the focused child; clicking a child focuses
+// it, and dragging a divider resizes the children either side of it.
+type Split struct {
+	UIs      []UI
+	Vertical bool
+	Focus    int
+
+	// Divs holds the position of each divider as a fraction of the area.
+	Divs []float64
+
+	dragging int
+	focused  bool
+}
+
+func NewHSplit(uis ...UI) *Split {
+	return newSplit(false, uis)
+}
+
+func NewVSplit(uis ...UI) *Split {
+	return newSplit(true, uis)
+}
+
+func newSplit(vertical bool, uis []UI) *Split {
+	s := &Split{
+		UIs:      uis,
+		Vertical: vertical,
+		dragging: -1,
+		focused:  true,
+	}
+	s.spread()
+	for i, ui := range uis {
+		if f, ok := ui.(Focusable); ok {
+			f.SetFocus(i == s.Focus)
+		}
+	}
+	return s
+}
+
+func (s *Split) spread() {
+	s.Divs = s.Divs[:0]
+	for l1 := 1; l1 < len(s.UIs); l1++ {
+		s.Divs = append(s.Divs, float64(l1)/float64(len(s.UIs)))
+	}
+}
+
+// Focused returns the child that currently receives keyboard events.
+func (s *Split) Focused() UI {
+	if s.Focus < 0 || s.Focus >= len(s.UIs) {
+		return nil
+	}
+	return s.UIs[s.Focus]
+}
+
+func (s *Split) SetFocus(focused bool) {
+	s.focused = focused
+	if f, ok := s.Focused().(Focusable); ok {
+		f.SetFocus(focused)
+	}
+}
+
+// FocusOn moves keyboard focus to child i.
+func (s *Split) FocusOn(i int) {
+	if i == s.Focus || i < 0 || i >= len(s.UIs) {
+		return
+	}
+	if f, ok := s.Focused().(Focusable); ok {
+		f.SetFocus(false)
+	}
+	s.Focus = i
+	if f, ok := s.Focused().(Focusable); ok {
+		f.SetFocus(s.focused)
+	}
+}
+
+// FocusNext cycles keyboard focus through the panes, descending into
+// nested splits. It reports whether focus has wrapped round to the first.
+func (s *Split) FocusNext() bool {
+	if inner, ok := s.Focused().(*Split); ok && !inner.FocusNext() {
+		return false
+	}
+	if len(s.UIs) == 0 {
+		return true
+	}
+	s.FocusOn((s.Focus + 1) % len(s.UIs))
+	return s.Focus == 0
+}
+
+// SplitFocused divides the focused pane, descending into nested splits,
+// between it and ui, side by side or, if vertical is set, stacked. ui is
+// given the focus.
+func (s *Split) SplitFocused(ui UI, vertical bool) {
+	if inner, ok := s.Focused().(*Split); ok {
+		inner.SplitFocused(ui, vertical)
+		return
+	}
+	if s.Vertical != vertical {
+		split := newSplit(vertical, []UI{s.UIs[s.Focus], ui})
+		split.SetFocus(s.focused)
+		split.FocusOn(1)
+		s.UIs[s.Focus] = split
+		return
+	}
+	// Halve the focused pane rather than respreading them all, so the
+	// others keep their sizes.
+	lo, hi := 0.0, 1.0
+	if s.Focus > 0 {
+		lo = s.Divs[s.Focus-1]
+	}
+	if s.Focus < len(s.Divs) {
+		hi = s.Divs[s.Focus]
+	}
+	i := s.Focus + 1
+	s.UIs = append(s.UIs[:i], append([]UI{ui}, s.UIs[i:]...)...)
+	s.Divs = append(s.Divs[:s.Focus], append([]float64{(lo + hi) / 2}, s.Divs[s.Focus:]...)...)
+	s.FocusOn(i)
+}
+
+// CloseFocused removes the focused pane, descending into nested splits, and
+// returns what should replace s: s itself, or its only remaining child.
+func (s *Split) CloseFocused() UI {
+	if len(s.UIs) == 0 {
+		return s
+	}
+	if inner, ok := s.Focused().(*Split); ok {
+		s.UIs[s.Focus] = inner.CloseFocused()
+		return s
+	}
+	if c, ok := s.Focused().(Closer); ok {
+		c.Close()
+	} else if f, ok := s.Focused().(Focusable); ok {
+		f.SetFocus(false)
+	}
+	s.UIs = append(s.UIs[:s.Focus], s.UIs[s.Focus+1:]...)
+	s.spread()
+	if s.Focus >= len(s.UIs) {
+		s.Focus = len(s.UIs) - 1
+	}
+	if f, ok := s.Focused().(Focusable); ok {
+		f.SetFocus(s.focused)
+	}
+	if len(s.UIs) == 1 {
+		return s.UIs[0]
+	}
+	return s
+}
+
+func (s *Split) length(r Rect) int {
+	if s.Vertical {
+		return r.H
+	}
+	return r.W
+}
+
+// areas lays out the children and the dividers between them.
+func (s *Split) areas(r Rect) (panes, divs []Rect) {
+	start := 0
+	total := s.length(r)
+	for i := range s.UIs {
+		end := total
+		if i < len(s.Divs) {
+			end = int(s.Divs[i] * float64(total))
+		}
+		if end < start {
+			end = start
+		}
+		if s.Vertical {
+			panes = append(panes, Rect{X: r.X, Y: r.Y + start, W: r.W, H: end - start})
+		} else {
+			panes = append(panes, Rect{X: r.X + start, Y: r.Y, W: end - start, H: r.H})
+		}
+		if i < len(s.Divs) {
+			if s.Vertical {
+				divs = append(divs, Rect{X: r.X, Y: r.Y + end, W: r.W, H: 1})
+			} else {
+				divs = append(divs, Rect{X: r.X + end, Y: r.Y, W: 1, H: r.H})
+			}
+		}
+		start = end + 1
+	}
+	return panes, divs
+}
+
+func (s *Split) Render(r Rect) {
+	panes, divs := s.areas(r)
+	for i, ui := range s.UIs {
+		if i != s.Focus {
+			ui.Render(panes[i])
+		}
+	}
+	for _, d := range divs {
+		ch := '│'
+		if s.Vertical {
+			ch = '─'
+		}
+		for l1 := d.Y; l1 < d.Y+d.H; l1++ {
+			for l2 := d.X; l2 < d.X+d.W; l2++ {
+				SetCell(l2, l1, ch, termbox.ColorWhite, termbox.ColorDefault)
+			}
+		}
+	}
+	// The focused pane is drawn last so that the cursor it places wins.
+	if ui := s.Focused(); ui != nil {
+		ui.Render(panes[s.Focus])
+	}
+}
+
+func (s *Split) Handle(r Rect, evt termbox.Event) bool {
+	panes, divs := s.areas(r)
+	if evt.Type == termbox.EventMouse {
+		if s.dragging >= 0 {
+			if evt.Key == termbox.MouseRelease || evt.Mod != termbox.ModMotion {
+				s.dragging = -1
+				if evt.Key == termbox.MouseRelease {
+					return true
+				}
+			} else {
+				s.drag(r, evt)
+				return true
+			}
+		}
+		for i, d := range divs {
+			if d.CheckEvent(evt) && evt.Key == termbox.MouseLeft {
+				s.dragging = i
+				return true
+			}
+		}
+		for i, p := range panes {
+			if p.CheckEvent(evt) {
+				if evt.Key == termbox.MouseLeft {
+					s.FocusOn(i)
+				}
+				return s.UIs[i].Handle(p, evt)
+			}
+		}
+		return false
+	}
+	if ui := s.Focused(); ui != nil {
+		return ui.Handle(panes[s.Focus], evt)
+	}
+	return false
+}
+
+func (s *Split) drag(r Rect, evt termbox.Event) {
+	total := s.length(r)
+	if total <= 0 {
+		return
+	}
+	pos := evt.MouseX - r.X
+	if s.Vertical {
+		pos = evt.MouseY - r.Y
+	}
+	div := float64(pos) / float64(total)
+	min, max := 0.0, 1.0
+	if s.dragging > 0 {
+		min = s.Divs[s.dragging-1]
+	}
+	if s.dragging < len(s.Divs)-1 {
+		max = s.Divs[s.dragging+1]
+	}
+	if div <= min || div >= max {
+		return
+	}
+	s.Divs[s.dragging] = div
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/andyleap/termbox-go"
+)
+
+type pane struct{ name string }
+
+func (p *pane) Render(r Rect)                         {}
+func (p *pane) Handle(r Rect, evt termbox.Event) bool { return false }
+
+func TestSplitFocused(t *testing.T) {
+	a, b, c, d := &pane{"a"}, &pane{"b"}, &pane{"c"}, &pane{"d"}
+	s := NewHSplit(a, b)
+	s.FocusOn(1)
+
+	s.SplitFocused(c, true)
+	inner, ok := s.UIs[1].(*Split)
+	if !ok || s.UIs[0] != a || inner.UIs[0] != b || inner.UIs[1] != c {
+		t.Fatalf("splitting b below: got %#v", s.UIs)
+	}
+	if !inner.Vertical || inner.Focused() != c {
+		t.Errorf("c should be below b and focused")
+	}
+
+	s.SplitFocused(d, true)
+	if len(inner.UIs) != 3 || inner.UIs[2] != d || inner.Focused() != d {
+		t.Fatalf("splitting c below again: got %#v", inner.UIs)
+	}
+	// c's half is shared with d, leaving b its half.
+	if want := []float64{0.5, 0.75}; inner.Divs[0] != want[0] || inner.Divs[1] != want[1] {
+		t.Errorf("dividers %v, want %v", inner.Divs, want)
+	}
+	if len(s.UIs) != 2 || s.Divs[0] != 0.5 {
+		t.Errorf("the outer split changed: %#v %v", s.UIs, s.Divs)
+	}
+}
+
+func TestFocusNextNested(t *testing.T) {
+	a, b, c := &pane{"a"}, &pane{"b"}, &pane{"c"}
+	s := NewHSplit(a, b)
+	s.FocusOn(1)
+	s.SplitFocused(c, true)
+	var order []UI
+	for l1 := 0; l1 < 4; l1++ {
+		s.FocusNext()
+		ui := s.Focused()
+		if sp, ok := ui.(*Split); ok {
+			ui = sp.Focused()
+		}
+		order = append(order, ui)
+	}
+	if want := []UI{a, b, c, a}; order[0] != want[0] || order[1] != want[1] || order[2] != want[2] || order[3] != want[3] {
+		t.Errorf("focus went %v, want a b c a", order)
+	}
+}
+
+type closingPane struct {
+	pane
+	closed bool
+}
+
+func (p *closingPane) Close() { p.closed = true }
+
+func TestCloseFocusedCloses(t *testing.T) {
+	a, b := &closingPane{pane: pane{"a"}}, &closingPane{pane: pane{"b"}}
+	s := NewHSplit(a, b)
+	s.FocusOn(1)
+	if got := s.CloseFocused(); got != a {
+		t.Fatalf("got %#v, want a left", got)
+	}
+	if !b.closed || a.closed {
+		t.Errorf("closed a %v, b %v, want only b", a.closed, b.closed)
+	}
+}
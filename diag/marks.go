@@ -0,0 +1,197 @@
+package diag
+
+import (
+	"path/filepath"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/andyleap/editor/buffer"
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+// Mark is a diagnostic placed in a buffer, covering the runes from Start up
+// to End.
+type Mark struct {
+	Start, End int
+	Message    string
+}
+
+type edit struct {
+	pos    int
+	insert bool
+}
+
+// Marks is a buffer.Styler that underlines the diagnostics found the last
+// time its buffer was checked, keeping them in place as the buffer is
+// edited.
+type Marks struct {
+	b *buffer.Buffer
+
+	// Post, if set, keeps Check from waiting for go vet to finish.
+	Post core.PostFunc
+
+	marks []Mark
+
+	// Edits made while a check is running are logged so that its results,
+	// which refer to the text as it was saved, can be moved to match.
+	gen     int
+	pending bool
+	log     []edit
+}
+
+func New(b *buffer.Buffer) *Marks {
+	return &Marks{
+		b: b,
+	}
+}
+
+// Marks returns the current diagnostics.
+func (m *Marks) Marks() []Mark {
+	return m.marks
+}
+
+// Line returns the diagnostics on line y.
+func (m *Marks) Line(y int) []Mark {
+	var marks []Mark
+	for _, mark := range m.marks {
+		if m.b.LineAt(mark.Start) == y {
+			marks = append(marks, mark)
+		}
+	}
+	return marks
+}
+
+// Check vets the buffer's file and replaces the marks with what it finds.
+// Files that aren't Go source are left unchecked, with no marks.
+func (m *Marks) Check() {
+	filename := m.b.Filename
+	if filename == "" {
+		return
+	}
+	if filepath.Ext(filename) != ".go" {
+		m.gen++
+		m.pending = false
+		m.marks = m.marks[:0]
+		return
+	}
+	text := []rune(m.b.Text())
+	m.gen++
+	m.pending = true
+	m.log = m.log[:0]
+	gen := m.gen
+
+	apply := func(diags []Diagnostic, err error) {
+		if gen != m.gen {
+			return
+		}
+		m.pending = false
+		if err != nil {
+			return
+		}
+		m.marks = m.marks[:0]
+		for _, d := range diags {
+			m.marks = append(m.marks, place(text, d))
+		}
+		for _, e := range m.log {
+			m.shift(e)
+		}
+		m.log = m.log[:0]
+	}
+
+	if m.Post == nil {
+		apply(Vet(filename))
+		return
+	}
+	go func() {
+		diags, err := Vet(filename)
+		m.Post(func() { apply(diags, err) })
+	}()
+}
+
+// place turns a diagnostic into a mark over text, underlining the
+// identifier it points at, or a single rune.
+func place(text []rune, d Diagnostic) Mark {
+	pos := 0
+	for line := 0; line < d.Line && pos < len(text); pos++ {
+		if text[pos] == '\n' {
+			line++
+		}
+	}
+	for col := 0; col < d.Col && pos < len(text) && text[pos] != '\n'; pos++ {
+		col += utf8.RuneLen(text[pos])
+	}
+	end := pos
+	for end < len(text) && (text[end] == '_' || unicode.IsLetter(text[end]) || unicode.IsDigit(text[end])) {
+		end++
+	}
+	if end == pos && pos < len(text) && text[pos] != '\n' {
+		end++
+	}
+	return Mark{Start: pos, End: end, Message: d.Message}
+}
+
+func (m *Marks) shift(e edit) {
+	for l1 := range m.marks {
+		mark := &m.marks[l1]
+		if e.insert {
+			if e.pos <= mark.Start {
+				mark.Start++
+			}
+			if e.pos < mark.End {
+				mark.End++
+			}
+			continue
+		}
+		if e.pos < mark.Start {
+			mark.Start--
+		}
+		if e.pos < mark.End {
+			mark.End--
+		}
+	}
+}
+
+// Mark flags lines with diagnostics in the buffer's gutter.
+func (m *Marks) Mark(y int) (ch rune, fg termbox.Attribute, ok bool) {
+	if len(m.Line(y)) == 0 {
+		return 0, 0, false
+	}
+	return '●', termbox.ColorRed, true
+}
+
+func (m *Marks) Style(pos int, ifg, ibg termbox.Attribute) (fg, bg termbox.Attribute) {
+	for _, mark := range m.marks {
+		if pos >= mark.Start && pos < mark.End {
+			return termbox.ColorRed | termbox.AttrUnderline, ibg
+		}
+	}
+	return ifg, ibg
+}
+
+func (m *Marks) Kind(pos int) buffer.Kind { return buffer.KindNormal }
+
+func (m *Marks) Insert(pos int) {
+	m.edit(edit{pos: pos, insert: true})
+}
+
+// Delete is told the position after the removed rune.
+func (m *Marks) Delete(pos int) {
+	m.edit(edit{pos: pos - 1})
+}
+
+func (m *Marks) edit(e edit) {
+	m.shift(e)
+	if m.pending {
+		m.log = append(m.log, e)
+	}
+}
+
+// Clear drops every mark, since the text they referred to has been
+// replaced wholesale.
+func (m *Marks) Clear() {
+	m.marks = nil
+	m.gen++
+	m.pending = false
+	m.log = m.log[:0]
+}
@@ -0,0 +1,20 @@
+package diag
+
+import (
+	"testing"
+
+	"github.com/andyleap/editor/buffer"
+)
+
+func TestCheckSkipsOtherFiles(t *testing.T) {
+	b := buffer.New([]rune("# Notes\n"))
+	b.Filename = "notes.md"
+	m := New(b)
+	m.Post = func(func()) { t.Fatal("vetted a Markdown file") }
+	// Marks left over from when the buffer was saved as Go.
+	m.marks = []Mark{{Start: 0, End: 1, Message: "stale"}}
+	m.Check()
+	if len(m.Marks()) != 0 || m.pending {
+		t.Errorf("got marks %v, pending %v, want none", m.Marks(), m.pending)
+	}
+}
@@ -0,0 +1,29 @@
+package diag
+
+import (
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+// Status is a status line that shows the diagnostic on the cursor's line,
+// and otherwise whatever Bar shows.
+type Status struct {
+	Marks *Marks
+	Bar   core.UI
+}
+
+func (s *Status) Render(r core.Rect) {
+	marks := s.Marks.Line(s.Marks.b.CurY)
+	if len(marks) == 0 {
+		s.Bar.Render(r)
+		return
+	}
+	for l1 := r.X; l1 < r.X+r.W; l1++ {
+		core.SetCell(l1, r.Y, ' ', termbox.ColorWhite, termbox.ColorRed)
+	}
+	core.RenderString(r.X, r.Y, marks[0].Message, termbox.ColorWhite|termbox.AttrBold, termbox.ColorRed)
+}
+
+func (s *Status) Handle(r core.Rect, evt termbox.Event) bool {
+	return s.Bar.Handle(r, evt)
+}
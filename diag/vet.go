@@ -0,0 +1,77 @@
+// Package diag runs the Go toolchain over saved files and marks the errors
+// it reports in their buffers.
+package diag
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is a single error reported against a file. Line is zero-based
+// and Col is a zero-based byte offset into the line.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Col     int
+	Message string
+}
+
+var diagLine = regexp.MustCompile(`^(?:vet: )?(.+\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// Vet runs `go vet` on the package containing filename, which also reports
+// anything that stops the package compiling, and returns what it found in
+// that file.
+func Vet(filename string) ([]Diagnostic, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(abs)
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return nil, err
+	}
+	var diags []Diagnostic
+	for _, d := range parse(dir, out) {
+		if d.File == abs {
+			diags = append(diags, d)
+		}
+	}
+	return diags, nil
+}
+
+// parse reads file:line:col: message lines out of the toolchain's output,
+// resolving file names relative to dir.
+func parse(dir string, out []byte) []Diagnostic {
+	var diags []Diagnostic
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		m := diagLine.FindStringSubmatch(strings.TrimSpace(s.Text()))
+		if m == nil {
+			continue
+		}
+		file := m[1]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		line, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		if col > 0 {
+			col--
+		}
+		diags = append(diags, Diagnostic{
+			File:    file,
+			Line:    line - 1,
+			Col:     col,
+			Message: m[4],
+		})
+	}
+	return diags
+}
@@ -0,0 +1,37 @@
+package dialogs
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/andyleap/editor/core/screentest"
+)
+
+func TestDialog(t *testing.T) {
+	d := &Dialog{
+		Message: "main.go has unsaved changes",
+		Options: []Option{{"Save", nil}, {"Discard", nil}, {"Cancel", nil}},
+	}
+	screentest.Golden(t, "dialog", screentest.Render(d, 60, 9).String())
+}
+
+func TestErrorDialog(t *testing.T) {
+	err := &os.PathError{Op: "open", Path: "/etc/motd", Err: os.ErrPermission}
+	d := NewErrorDialog("Could not save /etc/motd", err)
+	m := screentest.Render(d, 60, 12)
+	screentest.Golden(t, "error", m.String()+"\n"+screentest.Highlights(m))
+
+	d = NewErrorDialog("Could not save", errors.New("a much longer message that will not fit on one line of the dialog and so has to be wrapped"))
+	screentest.Golden(t, "error_wrap", screentest.Render(d, 60, 12).String())
+}
+
+func TestOpenDialog(t *testing.T) {
+	d := NewOpenDialog("testdata/tree")
+	screentest.Golden(t, "open", screentest.Render(d, 50, 30).String())
+}
+
+func TestSaveDialog(t *testing.T) {
+	d := NewSaveDialog("testdata/tree")
+	screentest.Golden(t, "save", screentest.Render(d, 50, 30).String())
+}
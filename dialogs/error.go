@@ -0,0 +1,110 @@
+package dialogs
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+// ErrorDialog reports an error, such as why a file could not be saved,
+// until it is dismissed.
+type ErrorDialog struct {
+	Title string
+	Err   error
+
+	// Close is called when the dialog is dismissed.
+	Close func()
+}
+
+func NewErrorDialog(title string, err error) *ErrorDialog {
+	return &ErrorDialog{
+		Title: title,
+		Err:   err,
+	}
+}
+
+// hint explains the commonest errors in plainer terms.
+func hint(err error) string {
+	switch {
+	case os.IsPermission(err):
+		return "You do not have permission to write there."
+	case errors.Is(err, syscall.ENOSPC):
+		return "The disk is full."
+	case errors.Is(err, syscall.EROFS):
+		return "The file system is read only."
+	}
+	return ""
+}
+
+// wrap breaks text into lines no wider than w.
+func wrap(text string, w int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for w > 0 && len(word) > w {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, word[:w])
+			word = word[w:]
+		}
+		if line != "" && len(line)+1+len(word) > w {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (d *ErrorDialog) layout(r core.Rect) (core.Rect, []string) {
+	w := r.W - 24
+	lines := wrap(d.Err.Error(), w)
+	if h := hint(d.Err); h != "" {
+		lines = append(lines, "")
+		lines = append(lines, wrap(h, w)...)
+	}
+	h := len(lines) + 4
+	return core.Rect{X: r.X + 10, Y: r.Y + (r.H-h)/2, W: r.W - 20, H: h}, lines
+}
+
+func (d *ErrorDialog) Render(r core.Rect) {
+	r, lines := d.layout(r)
+
+	core.Frame(r, termbox.ColorWhite, termbox.ColorRed)
+	core.RenderString(r.X+2, r.Y, " "+d.Title+" ", termbox.ColorWhite|termbox.AttrBold, termbox.ColorRed)
+	for i, line := range lines {
+		core.RenderString(r.X+2, r.Y+1+i, line, termbox.ColorWhite, termbox.ColorRed)
+	}
+	core.RenderString(r.X+r.W/2-2, r.Y+r.H-2, "[OK]", termbox.ColorWhite|termbox.AttrBold, termbox.ColorRed)
+}
+
+func (d *ErrorDialog) Handle(r core.Rect, evt termbox.Event) bool {
+	r, _ = d.layout(r)
+	dismiss := false
+	switch evt.Type {
+	case termbox.EventKey:
+		switch evt.Key {
+		case termbox.KeyEnter, termbox.KeyEsc, termbox.KeySpace:
+			dismiss = true
+		}
+	case termbox.EventMouse:
+		dismiss = evt.Key == termbox.MouseLeft && evt.MouseY == r.Y+r.H-2 &&
+			evt.MouseX >= r.X+r.W/2-2 && evt.MouseX < r.X+r.W/2+2
+	}
+	if dismiss && d.Close != nil {
+		d.Close()
+	}
+	return true
+}
@@ -68,7 +68,7 @@ func (d *SaveDialog) Render(r core.Rect) {
 	}
 
 	for x := r.X + 1; x <= r.Y+r.W-1; x++ {
-		termbox.SetCell(x, r.Y+r.H-2, ' ', termbox.ColorWhite, termbox.ColorBlue)
+		core.SetCell(x, r.Y+r.H-2, ' ', termbox.ColorWhite, termbox.ColorBlue)
 	}
 	core.RenderString(r.X+1, r.Y+r.H-2, d.fileName, termbox.ColorWhite, termbox.ColorBlue)
 
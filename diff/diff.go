@@ -0,0 +1,193 @@
+// Package diff compares texts line by line.
+package diff
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Kind says whether a line is common to both texts or only in one.
+type Kind int
+
+const (
+	Equal Kind = iota
+	Delete
+	Insert
+)
+
+// Line is a line of a diff. Its Text includes the newline ending it, which
+// only the last line of a text can be without.
+type Line struct {
+	Kind Kind
+	Text string
+}
+
+// maxCells bounds the table used to find the longest common subsequence;
+// beyond it the differing middle of the texts is reported as replaced
+// wholesale.
+const maxCells = 4 << 20
+
+// Lines returns the edits that turn a into b.
+func Lines(a, b []string) []Line {
+	var out []Line
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		out = append(out, Line{Equal, a[pre]})
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	out = append(out, middle(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, l := range a[len(a)-suf:] {
+		out = append(out, Line{Equal, l})
+	}
+	return out
+}
+
+func middle(a, b []string) []Line {
+	var out []Line
+	if len(a)*len(b) > maxCells {
+		for _, l := range a {
+			out = append(out, Line{Delete, l})
+		}
+		for _, l := range b {
+			out = append(out, Line{Insert, l})
+		}
+		return out
+	}
+	// lcs[l1][l2] is the length of the longest common subsequence of
+	// a[l1:] and b[l2:].
+	lcs := make([][]int, len(a)+1)
+	for l1 := range lcs {
+		lcs[l1] = make([]int, len(b)+1)
+	}
+	for l1 := len(a) - 1; l1 >= 0; l1-- {
+		for l2 := len(b) - 1; l2 >= 0; l2-- {
+			if a[l1] == b[l2] {
+				lcs[l1][l2] = lcs[l1+1][l2+1] + 1
+			} else if lcs[l1+1][l2] >= lcs[l1][l2+1] {
+				lcs[l1][l2] = lcs[l1+1][l2]
+			} else {
+				lcs[l1][l2] = lcs[l1][l2+1]
+			}
+		}
+	}
+	l1, l2 := 0, 0
+	for l1 < len(a) && l2 < len(b) {
+		switch {
+		case a[l1] == b[l2]:
+			out = append(out, Line{Equal, a[l1]})
+			l1++
+			l2++
+		case lcs[l1+1][l2] >= lcs[l1][l2+1]:
+			out = append(out, Line{Delete, a[l1]})
+			l1++
+		default:
+			out = append(out, Line{Insert, b[l2]})
+			l2++
+		}
+	}
+	for ; l1 < len(a); l1++ {
+		out = append(out, Line{Delete, a[l1]})
+	}
+	for ; l2 < len(b); l2++ {
+		out = append(out, Line{Insert, b[l2]})
+	}
+	return out
+}
+
+// split breaks text into lines, keeping their newlines so that a last line
+// without one differs from the same line with it.
+func split(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Unified returns a unified diff, with three lines of context, turning
+// text a, named aName, into text b, named bName. It is empty if the texts
+// are the same.
+func Unified(aName, bName, a, b string) string {
+	lines := Lines(split(a), split(b))
+	const context = 3
+
+	var sb strings.Builder
+	// aLine and bLine count the lines of each text passed so far.
+	aLine, bLine := 0, 0
+	for l1 := 0; l1 < len(lines); {
+		if lines[l1].Kind == Equal {
+			aLine++
+			bLine++
+			l1++
+			continue
+		}
+		// A hunk runs from a little before this change to a little after
+		// the last change less than two contexts' worth of lines further
+		// on.
+		start := l1 - context
+		if start < 0 {
+			start = 0
+		}
+		end := l1
+		for l2 := l1; l2 < len(lines); l2++ {
+			if lines[l2].Kind != Equal {
+				end = l2 + 1
+			} else if l2-end >= 2*context {
+				break
+			}
+		}
+		end += context
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		aStart, bStart := aLine-(l1-start), bLine-(l1-start)
+		aCount, bCount := 0, 0
+		for _, l := range lines[start:end] {
+			if l.Kind != Insert {
+				aCount++
+			}
+			if l.Kind != Delete {
+				bCount++
+			}
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("--- " + aName + "\n+++ " + bName + "\n")
+		}
+		sb.WriteString("@@ -" + hunkRange(aStart, aCount) + " +" + hunkRange(bStart, bCount) + " @@\n")
+		for _, l := range lines[start:end] {
+			switch l.Kind {
+			case Equal:
+				sb.WriteString(" ")
+			case Delete:
+				sb.WriteString("-")
+			case Insert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(l.Text)
+			if !strings.HasSuffix(l.Text, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		aLine, bLine = aStart+aCount, bStart+bCount
+		l1 = end
+	}
+	return sb.String()
+}
+
+// hunkRange formats the lines of one text a hunk covers as diff -u does:
+// an empty range is given by the line before it, and a count of one is
+// left out.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return strconv.Itoa(start) + ",0"
+	case 1:
+		return strconv.Itoa(start + 1)
+	}
+	return strconv.Itoa(start+1) + "," + strconv.Itoa(count)
+}
@@ -0,0 +1,187 @@
+package diff
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// numbered returns n lines, line1 to lineN.
+func numbered(n int) string {
+	var sb strings.Builder
+	for l1 := 1; l1 <= n; l1++ {
+		sb.WriteString("line" + strconv.Itoa(l1) + "\n")
+	}
+	return sb.String()
+}
+
+// replace replaces line n of text, counting from 1, with line.
+func replace(text string, n int, line string) string {
+	lines := strings.SplitAfter(text, "\n")
+	lines[n-1] = line + "\n"
+	return strings.Join(lines, "")
+}
+
+// The expected output is that of GNU diff -u --label a --label b.
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"same", numbered(10), numbered(10), ""},
+		{"from empty", "", "a\nb\n", `--- a
++++ b
+@@ -0,0 +1,2 @@
++a
++b
+`},
+		{"to empty", "a\nb\n", "", `--- a
++++ b
+@@ -1,2 +0,0 @@
+-a
+-b
+`},
+		{"one line", "a\n", "b\n", `--- a
++++ b
+@@ -1 +1 @@
+-a
++b
+`},
+		{"middle", numbered(20), replace(numbered(20), 10, "changed"), `--- a
++++ b
+@@ -7,7 +7,7 @@
+ line7
+ line8
+ line9
+-line10
++changed
+ line11
+ line12
+ line13
+`},
+		{"start and end", numbered(10), replace(replace(numbered(10), 1, "first"), 10, "last"), `--- a
++++ b
+@@ -1,4 +1,4 @@
+-line1
++first
+ line2
+ line3
+ line4
+@@ -7,4 +7,4 @@
+ line7
+ line8
+ line9
+-line10
++last
+`},
+		// Six unchanged lines apart, the changes share a hunk...
+		{"merged", numbered(20), replace(replace(numbered(20), 5, "x"), 12, "y"), `--- a
++++ b
+@@ -2,14 +2,14 @@
+ line2
+ line3
+ line4
+-line5
++x
+ line6
+ line7
+ line8
+ line9
+ line10
+ line11
+-line12
++y
+ line13
+ line14
+ line15
+`},
+		// ...but seven apart they don't.
+		{"separate", numbered(20), replace(replace(numbered(20), 5, "x"), 13, "y"), `--- a
++++ b
+@@ -2,7 +2,7 @@
+ line2
+ line3
+ line4
+-line5
++x
+ line6
+ line7
+ line8
+@@ -10,7 +10,7 @@
+ line10
+ line11
+ line12
+-line13
++y
+ line14
+ line15
+ line16
+`},
+		{"insert", numbered(10), strings.Replace(numbered(10), "line5\n", "line5\nnew\n", 1), `--- a
++++ b
+@@ -3,6 +3,7 @@
+ line3
+ line4
+ line5
++new
+ line6
+ line7
+ line8
+`},
+		{"delete", numbered(10), strings.Replace(numbered(10), "line5\n", "", 1), `--- a
++++ b
+@@ -2,7 +2,6 @@
+ line2
+ line3
+ line4
+-line5
+ line6
+ line7
+ line8
+`},
+		{"final newline added", "a\nb", "a\nb\n", `--- a
++++ b
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`},
+		{"final newline removed", "a\nb\n", "a\nb", `--- a
++++ b
+@@ -1,2 +1,2 @@
+ a
+-b
++b
+\ No newline at end of file
+`},
+		{"last line without newline", "a\nb", "a\nc", `--- a
++++ b
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`},
+	}
+	for _, test := range tests {
+		if got := Unified("a", "b", test.a, test.b); got != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	got := Lines([]string{"a", "b", "c", "d"}, []string{"a", "c", "x", "d"})
+	want := []Line{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}, {Insert, "x"}, {Equal, "d"}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d is %v, want %v", i, got[i], want[i])
+		}
+	}
+}
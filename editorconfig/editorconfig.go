@@ -0,0 +1,148 @@
+// Package editorconfig finds the .editorconfig files that apply to a file
+// and works out the properties they give it.
+package editorconfig
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Name is the name of the files looked for.
+const Name = ".editorconfig"
+
+// Properties maps lowercased property names to their values.
+type Properties map[string]string
+
+// Int returns the integer value of key.
+func (p Properties) Int(key string) (int, bool) {
+	n, err := strconv.Atoi(p[key])
+	return n, err == nil && n > 0
+}
+
+// Bool returns the boolean value of key, and whether it is set to one.
+func (p Properties) Bool(key string) (value, ok bool) {
+	switch p[key] {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+	return false, false
+}
+
+type section struct {
+	glob  string
+	props Properties
+}
+
+type file struct {
+	dir      string
+	root     bool
+	sections []section
+}
+
+// parse reads an .editorconfig file. Malformed lines are ignored.
+func parse(path string) (*file, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ec := &file{dir: filepath.Dir(path)}
+	var cur *section
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.LastIndex(line, "]")
+			if end < 0 {
+				continue
+			}
+			ec.sections = append(ec.sections, section{glob: line[1:end], props: Properties{}})
+			cur = &ec.sections[len(ec.sections)-1]
+			continue
+		}
+		eq := strings.IndexAny(line, "=:")
+		if eq < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:eq]))
+		value := strings.TrimSpace(line[eq+1:])
+		if key != "indent_size" || strings.ToLower(value) == "tab" {
+			value = strings.ToLower(value)
+		}
+		if cur == nil {
+			if key == "root" {
+				ec.root = value == "true"
+			}
+			continue
+		}
+		cur.props[key] = value
+	}
+	return ec, s.Err()
+}
+
+// Lookup returns the properties given to filename by the .editorconfig
+// files in its directory and those above, stopping at one marked as the
+// root. Nearer files, and later sections within a file, take precedence.
+func Lookup(filename string) (Properties, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	var files []*file
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		ec, err := parse(filepath.Join(dir, Name))
+		if err == nil {
+			files = append(files, ec)
+			if ec.root {
+				break
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	props := Properties{}
+	for l1 := len(files) - 1; l1 >= 0; l1-- {
+		ec := files[l1]
+		rel, err := filepath.Rel(ec.dir, abs)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, sec := range ec.sections {
+			if match(sec.glob, rel) {
+				for k, v := range sec.props {
+					props[k] = v
+				}
+			}
+		}
+	}
+	for k, v := range props {
+		if v == "unset" {
+			delete(props, k)
+		}
+	}
+	// tab_width defaults to indent_size, and indent_size to tab_width
+	// when indenting with tabs.
+	if _, ok := props["tab_width"]; !ok {
+		if _, ok := props.Int("indent_size"); ok {
+			props["tab_width"] = props["indent_size"]
+		}
+	}
+	if _, ok := props["indent_size"]; !ok && props["indent_style"] == "tab" {
+		props["indent_size"] = "tab"
+	}
+	return props, nil
+}
@@ -0,0 +1,107 @@
+package editorconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTree writes files, keyed by slash separated paths, under dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		// Above the root, so never used.
+		".editorconfig": "[*]\ninsert_final_newline = true\n",
+		"proj/.editorconfig": `# The project's settings.
+root = true
+
+[*]
+indent_style = space
+indent_size = 4
+end_of_line = lf
+charset = utf-8
+
+; Later sections win.
+[*.md]
+charset = latin1
+
+[{*.go,Makefile}]
+indent_style = tab
+`,
+		"proj/sub/.editorconfig": `[*]
+indent_size = 2
+end_of_line = unset
+
+[*.go]
+trim_trailing_whitespace = true
+`,
+		"proj/tabs/.editorconfig": "root = true\n[*]\nIndent_Style = Tab\nbogus line\n",
+	})
+
+	tests := []struct {
+		file string
+		want Properties
+	}{
+		{"proj/a.go", Properties{
+			"indent_style": "tab", "indent_size": "4", "tab_width": "4",
+			"end_of_line": "lf", "charset": "utf-8",
+		}},
+		{"proj/notes.md", Properties{
+			"indent_style": "space", "indent_size": "4", "tab_width": "4",
+			"end_of_line": "lf", "charset": "latin1",
+		}},
+		// The nearer file overrides, and unset removes a property.
+		{"proj/sub/a.go", Properties{
+			"indent_style": "tab", "indent_size": "2", "tab_width": "2",
+			"charset": "utf-8", "trim_trailing_whitespace": "true",
+		}},
+		{"proj/sub/deeper/Makefile", Properties{
+			"indent_style": "tab", "indent_size": "2", "tab_width": "2",
+			"charset": "utf-8",
+		}},
+		// Names and values are lowercased, and tab indentation has an
+		// indent_size of tab.
+		{"proj/tabs/a.txt", Properties{"indent_style": "tab", "indent_size": "tab"}},
+	}
+	for _, test := range tests {
+		got, err := Lookup(filepath.Join(dir, filepath.FromSlash(test.file)))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.file, got, test.want)
+		}
+	}
+}
+
+func TestProperties(t *testing.T) {
+	p := Properties{"indent_size": "4", "tab_width": "0", "trim": "false", "insert": "yes"}
+	if n, ok := p.Int("indent_size"); n != 4 || !ok {
+		t.Errorf("Int(indent_size) = %d, %v, want 4, true", n, ok)
+	}
+	if _, ok := p.Int("tab_width"); ok {
+		t.Error("Int(tab_width) accepted 0")
+	}
+	if v, ok := p.Bool("trim"); v || !ok {
+		t.Errorf("Bool(trim) = %v, %v, want false, true", v, ok)
+	}
+	if _, ok := p.Bool("insert"); ok {
+		t.Error("Bool(insert) accepted yes")
+	}
+}
@@ -0,0 +1,144 @@
+package editorconfig
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// match reports whether the section glob matches path, which is slash
+// separated and relative to the directory of the .editorconfig file.
+func match(glob, path string) bool {
+	re, ranges, err := compile(glob)
+	if err != nil {
+		return false
+	}
+	m := re.FindStringSubmatch(path)
+	if m == nil {
+		return false
+	}
+	for i, rg := range ranges {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil || n < rg[0] || n > rg[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// compile turns a glob into a regexp. Numeric {a..b} ranges become
+// capture groups, whose bounds are returned to be checked separately.
+func compile(glob string) (*regexp.Regexp, [][2]int, error) {
+	// A glob without a slash matches files of that name in any directory.
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	} else if glob[0] == '/' {
+		glob = glob[1:]
+	}
+
+	var sb strings.Builder
+	var ranges [][2]int
+	braces := 0
+	rs := []rune(glob)
+	sb.WriteString("^")
+	for l1 := 0; l1 < len(rs); l1++ {
+		ch := rs[l1]
+		switch ch {
+		case '\\':
+			if l1+1 < len(rs) {
+				l1++
+				sb.WriteString(regexp.QuoteMeta(string(rs[l1])))
+			}
+		case '*':
+			if l1+1 < len(rs) && rs[l1+1] == '*' {
+				l1++
+				if l1+1 < len(rs) && rs[l1+1] == '/' {
+					l1++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := closing(rs, l1, ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := string(rs[l1+1 : end])
+			l1 = end
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+		case '{':
+			end := closing(rs, l1, '}')
+			if end < 0 {
+				sb.WriteString(`\{`)
+				continue
+			}
+			body := string(rs[l1+1 : end])
+			if lo, hi, ok := numRange(body); ok {
+				l1 = end
+				ranges = append(ranges, [2]int{lo, hi})
+				sb.WriteString(`([+-]?\d+)`)
+				continue
+			}
+			if !strings.Contains(body, ",") {
+				l1 = end
+				sb.WriteString(regexp.QuoteMeta("{" + body + "}"))
+				continue
+			}
+			braces++
+			sb.WriteString("(?:")
+		case '}':
+			if braces > 0 {
+				braces--
+				sb.WriteString(")")
+			} else {
+				sb.WriteString(`\}`)
+			}
+		case ',':
+			if braces > 0 {
+				sb.WriteString("|")
+			} else {
+				sb.WriteString(",")
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	return re, ranges, err
+}
+
+// closing returns the index of the first ch after rs[start], or -1.
+func closing(rs []rune, start int, ch rune) int {
+	for l1 := start + 1; l1 < len(rs); l1++ {
+		if rs[l1] == ch {
+			return l1
+		}
+	}
+	return -1
+}
+
+func numRange(s string) (lo, hi int, ok bool) {
+	parts := strings.Split(s, "..")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	lo, err1 := strconv.Atoi(parts[0])
+	hi, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return lo, hi, true
+}
@@ -0,0 +1,61 @@
+package editorconfig
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		want       bool
+	}{
+		// Without a slash a glob matches in any directory.
+		{"*", "a.go", true},
+		{"*", "dir/a.go", true},
+		{"*.go", "x/y/a.go", true},
+		{"*.go", "a.go.txt", false},
+		{"Makefile", "sub/Makefile", true},
+		{"a?c", "abc", true},
+		{"a?c", "a/c", false},
+
+		// With one it is anchored to the .editorconfig's directory.
+		{"/*.go", "a.go", true},
+		{"/*.go", "x/a.go", false},
+		{"lib/*.js", "lib/a.js", true},
+		{"lib/*.js", "lib/x/a.js", false},
+		{"lib/*.js", "x/lib/a.js", false},
+		{"lib/**.js", "lib/x/y/a.js", true},
+		{"**/test/*.go", "test/a.go", true},
+		{"**/test/*.go", "x/test/a.go", true},
+		{"**/test/*.go", "x/test/y/a.go", false},
+
+		{"*.{js,py}", "a.js", true},
+		{"*.{js,py}", "a.py", true},
+		{"*.{js,py}", "a.go", false},
+		{"{package.json,.travis.yml}", "package.json", true},
+		{"{package.json,.travis.yml}", "x/.travis.yml", true},
+		{"{a,{b,c}}.txt", "c.txt", true},
+		{"{single}", "{single}", true},
+		{"{single}", "single", false},
+
+		{"[abc].txt", "b.txt", true},
+		{"[abc].txt", "d.txt", false},
+		{"[!abc].txt", "d.txt", true},
+		{"[!abc].txt", "a.txt", false},
+		{"[a-c]x", "bx", true},
+		{"[abc", "[abc", true},
+
+		{"file{1..3}.txt", "file2.txt", true},
+		{"file{1..3}.txt", "file4.txt", false},
+		{"file{3..1}.txt", "file1.txt", true},
+		{"v{-2..2}", "v-1", true},
+		{"v{-2..2}", "v-3", false},
+
+		{`\*.md`, "*.md", true},
+		{`\*.md`, "a.md", false},
+		{"a.b", "axb", false},
+	}
+	for _, test := range tests {
+		if got := match(test.glob, test.path); got != test.want {
+			t.Errorf("match(%q, %q) = %v, want %v", test.glob, test.path, got, test.want)
+		}
+	}
+}
@@ -1,65 +1,337 @@
 package find
 
 import (
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
 	"github.com/andyleap/editor/buffer"
 	"github.com/andyleap/editor/core"
 
 	"github.com/andyleap/termbox-go"
 )
 
+const (
+	fieldSearch = iota
+	fieldReplace
+)
+
+// Match is the location of one hit in the buffer, in runes, along with the
+// byte offsets of its submatches in the text it was found in.
+type Match struct {
+	Start, End int
+
+	sub []int
+}
+
 type FindPanel struct {
-	searchString string
-	selected     bool
-	curPos       int
-	Buf          *buffer.Buffer
+	searchString  []rune
+	replaceString []rune
+	field         int
+	selected      bool
+	curPos        int
+	Buf           *buffer.Buffer
+
+	Regex      bool
+	IgnoreCase bool
+	WholeWord  bool
+
+	re      *regexp.Regexp
+	err     error
+	text    string
+	matches []Match
+
+	cacheBuf *buffer.Buffer
+	cacheRev int
+	cacheKey string
+	gen      int
+
+	// origin is where the cursor was when typing into the search field
+	// began; incremental matches are looked for from here.
+	origin, originSel int
+	searching         bool
 }
 
 func (f *FindPanel) Area(r core.Rect) core.Rect {
-	return core.Rect{X: r.X + r.W/2, Y: r.Y, W: r.W - (r.W / 2), H: 1}
+	return core.Rect{X: r.X + r.W/2, Y: r.Y, W: r.W - (r.W / 2), H: 2}
+}
+
+type toggle struct {
+	label string
+	on    *bool
+}
+
+func (f *FindPanel) toggles() []toggle {
+	return []toggle{
+		{".*", &f.Regex},
+		{"Aa", &f.IgnoreCase},
+		{"W", &f.WholeWord},
+	}
 }
 
+// The search row ends with the toggles, the match count and the ⋁/⋀
+// buttons; the replace row with the Next and All buttons.
+func (f *FindPanel) toggleX(r core.Rect) int { return r.X + r.W - 20 }
+func (f *FindPanel) countX(r core.Rect) int  { return r.X + r.W - 11 }
+func (f *FindPanel) nextX(r core.Rect) int   { return r.X + r.W - 10 }
+func (f *FindPanel) allX(r core.Rect) int    { return r.X + r.W - 4 }
+
 func (f *FindPanel) Render(r core.Rect) {
 	r = f.Area(r)
 
-	for l1 := r.X; l1 < r.X+r.W; l1++ {
-		termbox.SetCell(l1, r.Y, ' ', termbox.ColorWhite, termbox.ColorBlue)
+	for l1 := r.Y; l1 < r.Y+r.H; l1++ {
+		for l2 := r.X; l2 < r.X+r.W; l2++ {
+			core.SetCell(l2, l1, ' ', termbox.ColorWhite, termbox.ColorBlue)
+		}
+	}
+	core.RenderString(r.X, r.Y, string(f.searchString), termbox.ColorWhite, termbox.ColorBlue)
+	core.RenderString(r.X, r.Y+1, string(f.replaceString), termbox.ColorWhite, termbox.ColorBlue)
+
+	x := f.toggleX(r)
+	for _, t := range f.toggles() {
+		fg, bg := termbox.ColorWhite, termbox.ColorBlue
+		if *t.on {
+			fg, bg = termbox.ColorBlue, termbox.ColorWhite
+		}
+		core.RenderString(x, r.Y, t.label, fg, bg)
+		x += len(t.label) + 1
 	}
-	core.RenderString(r.X, r.Y, f.searchString, termbox.ColorWhite, termbox.ColorBlue)
-	termbox.SetCell(r.X+r.W-2, r.Y, '⋁', termbox.ColorBlue, termbox.ColorWhite)
-	termbox.SetCell(r.X+r.W-1, r.Y, '⋀', termbox.ColorBlue, termbox.ColorWhite)
+
+	f.update()
+	count := ""
+	fg := termbox.ColorWhite
+	if f.err != nil {
+		count, fg = "error", termbox.ColorRed|termbox.AttrBold
+	} else if len(f.searchString) > 0 {
+		count = strconv.Itoa(f.Current()+1) + "/" + strconv.Itoa(len(f.matches))
+	}
+	core.RenderString(f.countX(r)-len(count), r.Y, count, fg, termbox.ColorBlue)
+
+	core.SetCell(r.X+r.W-2, r.Y, '⋁', termbox.ColorBlue, termbox.ColorWhite)
+	core.SetCell(r.X+r.W-1, r.Y, '⋀', termbox.ColorBlue, termbox.ColorWhite)
+	core.RenderString(f.nextX(r), r.Y+1, "Next", termbox.ColorBlue, termbox.ColorWhite)
+	core.RenderString(f.allX(r), r.Y+1, "All", termbox.ColorBlue, termbox.ColorWhite)
+
 	if f.selected {
-		termbox.SetCursor(r.X+f.curPos, r.Y)
+		core.SetCursor(r.X+f.curPos, r.Y+f.field)
 	}
 }
 
-func (f *FindPanel) Search(up bool) {
-	step := 1
-	if up {
-		step = -1
+func (f *FindPanel) pattern() string {
+	expr := string(f.searchString)
+	if !f.Regex {
+		expr = regexp.QuoteMeta(expr)
 	}
-	p := f.Buf.Pos() + step
-	if p < 0 || p >= f.Buf.GB.Len() {
+	if f.WholeWord {
+		expr = `\b(?:` + expr + `)\b`
+	}
+	flags := "(?m)"
+	if f.IgnoreCase {
+		flags = "(?mi)"
+	}
+	return flags + expr
+}
+
+// update recompiles the pattern and rescans the buffer if either has
+// changed since the last call.
+func (f *FindPanel) update() {
+	if f.Buf == nil {
 		return
 	}
-search:
-	for ; p >= 0 && p < f.Buf.GB.Len(); p += step {
-		for i, c := range f.searchString {
-			if p+i >= f.Buf.GB.Len() {
-				continue search
-			}
-			if c != f.Buf.GB.Get(p+i) {
-				continue search
+	key := ""
+	if len(f.searchString) > 0 {
+		key = f.pattern()
+	}
+	if f.cacheBuf == f.Buf && f.cacheRev == f.Buf.Rev() && f.cacheKey == key {
+		return
+	}
+	f.cacheBuf, f.cacheRev, f.cacheKey = f.Buf, f.Buf.Rev(), key
+	f.gen++
+	f.re, f.err, f.matches = nil, nil, f.matches[:0]
+	if key == "" {
+		return
+	}
+	f.re, f.err = regexp.Compile(key)
+	if f.err != nil {
+		return
+	}
+	f.text = f.Buf.Text()
+	bytePos, runePos := 0, 0
+	for _, m := range f.re.FindAllStringSubmatchIndex(f.text, -1) {
+		runePos += utf8.RuneCountInString(f.text[bytePos:m[0]])
+		start := runePos
+		runePos += utf8.RuneCountInString(f.text[m[0]:m[1]])
+		bytePos = m[1]
+		f.matches = append(f.matches, Match{Start: start, End: runePos, sub: m})
+	}
+}
+
+// Matches returns every match of the current search in the buffer.
+func (f *FindPanel) Matches() []Match {
+	f.update()
+	return f.matches
+}
+
+// Current returns the index of the match starting at the cursor, or -1.
+func (f *FindPanel) Current() int {
+	f.update()
+	pos := f.Buf.Pos()
+	for i, m := range f.matches {
+		if m.Start == pos {
+			return i
+		}
+		if m.Start > pos {
+			break
+		}
+	}
+	return -1
+}
+
+func (f *FindPanel) selectMatch(m Match) {
+	f.Buf.SetPos(m.Start)
+	f.Buf.Sel = -1
+	if m.End > m.Start {
+		f.Buf.Sel = m.End
+	}
+}
+
+// Search moves to the next match after the cursor, or the previous one if
+// up is set, wrapping around the ends of the buffer.
+func (f *FindPanel) Search(up bool) {
+	f.update()
+	if len(f.matches) == 0 {
+		return
+	}
+	pos := f.Buf.Pos()
+	if up {
+		for l1 := len(f.matches) - 1; l1 >= 0; l1-- {
+			if f.matches[l1].Start < pos {
+				f.selectMatch(f.matches[l1])
+				return
 			}
 		}
-		f.Buf.SetPos(p)
+		f.selectMatch(f.matches[len(f.matches)-1])
+		return
+	}
+	for _, m := range f.matches {
+		if m.Start > pos {
+			f.selectMatch(m)
+			return
+		}
+	}
+	f.selectMatch(f.matches[0])
+}
+
+func (f *FindPanel) replacement(m Match) []rune {
+	if !f.Regex {
+		return f.replaceString
+	}
+	return []rune(string(f.re.ExpandString(nil, string(f.replaceString), f.text, m.sub)))
+}
+
+// ReplaceNext replaces the match at the cursor, if there is one, and moves
+// on to the next match.
+func (f *FindPanel) ReplaceNext() {
+	i := f.Current()
+	if i < 0 {
+		f.Search(false)
+		return
+	}
+	m := f.matches[i]
+	text := f.replacement(m)
+	f.Buf.BeginEdit()
+	f.Buf.Sel = -1
+	f.Buf.Remove(m.Start, m.End-m.Start)
+	f.Buf.InsertAt(m.Start, text)
+	f.Buf.SetPos(m.Start + len(text))
+	f.Buf.EndEdit()
+	f.Search(false)
+}
+
+// ReplaceAll replaces every match as a single undoable edit.
+func (f *FindPanel) ReplaceAll() {
+	f.update()
+	if len(f.matches) == 0 {
 		return
 	}
-	return
+	matches := append([]Match(nil), f.matches...)
+	f.Buf.BeginEdit()
+	f.Buf.Sel = -1
+	pos := f.Buf.Pos()
+	for l1 := len(matches) - 1; l1 >= 0; l1-- {
+		m := matches[l1]
+		text := f.replacement(m)
+		f.Buf.Remove(m.Start, m.End-m.Start)
+		f.Buf.InsertAt(m.Start, text)
+		if pos >= m.End {
+			pos += len(text) - (m.End - m.Start)
+		} else if pos > m.Start {
+			pos = m.Start
+		}
+	}
+	f.Buf.SetPos(pos)
+	f.Buf.EndEdit()
 }
 
 func (f *FindPanel) Focus() {
 	f.selected = true
+	f.field = fieldSearch
 	f.curPos = len(f.searchString)
+	f.searching = false
+	f.begin()
+}
+
+// begin records the cursor position incremental search starts from.
+func (f *FindPanel) begin() {
+	if f.searching || f.Buf == nil {
+		return
+	}
+	f.origin, f.originSel = f.Buf.Pos(), f.Buf.Sel
+	f.searching = true
+}
+
+// incremental moves to the first match at or after the origin, wrapping
+// to the top, or back to the origin if there is none.
+func (f *FindPanel) incremental() {
+	f.update()
+	if !f.searching {
+		return
+	}
+	for _, m := range f.matches {
+		if m.Start >= f.origin {
+			f.selectMatch(m)
+			return
+		}
+	}
+	if len(f.matches) > 0 {
+		f.selectMatch(f.matches[0])
+		return
+	}
+	f.Buf.SetPos(f.origin)
+	f.Buf.Sel = f.originSel
+}
+
+// accept ends incremental search, leaving the cursor on the match.
+func (f *FindPanel) accept() {
+	f.selected = false
+	f.searching = false
+}
+
+// cancel ends incremental search and returns the cursor to where it began.
+func (f *FindPanel) cancel() {
+	if f.searching {
+		f.Buf.SetPos(f.origin)
+		f.Buf.Sel = f.originSel
+	}
+	f.selected = false
+	f.searching = false
+}
+
+func (f *FindPanel) fieldText() *[]rune {
+	if f.field == fieldReplace {
+		return &f.replaceString
+	}
+	return &f.searchString
 }
 
 func (f *FindPanel) Handle(r core.Rect, evt termbox.Event) bool {
@@ -67,56 +339,122 @@ func (f *FindPanel) Handle(r core.Rect, evt termbox.Event) bool {
 
 	if evt.Type == termbox.EventMouse && evt.Key == termbox.MouseLeft {
 		if r.CheckEvent(evt) {
-			if evt.MouseX == r.X+r.W-1 {
-				f.selected = false
-				f.Search(true)
-			}
-			if evt.MouseX == r.X+r.W-2 {
-				f.selected = false
-				f.Search(false)
+			row := evt.MouseY - r.Y
+			if row == fieldSearch {
+				if evt.MouseX == r.X+r.W-1 {
+					f.Search(true)
+					return true
+				}
+				if evt.MouseX == r.X+r.W-2 {
+					f.Search(false)
+					return true
+				}
+				x := f.toggleX(r)
+				for _, t := range f.toggles() {
+					if evt.MouseX >= x && evt.MouseX < x+len(t.label) {
+						*t.on = !*t.on
+						f.incremental()
+						return true
+					}
+					x += len(t.label) + 1
+				}
+			} else {
+				if evt.MouseX >= f.nextX(r) && evt.MouseX < f.nextX(r)+4 {
+					f.ReplaceNext()
+					return true
+				}
+				if evt.MouseX >= f.allX(r) && evt.MouseX < f.allX(r)+3 {
+					f.ReplaceAll()
+					return true
+				}
 			}
 			f.selected = true
+			f.field = row
 			f.curPos = evt.MouseX - r.X
-			if f.curPos > len(f.searchString) {
-				f.curPos = len(f.searchString)
+			if text := f.fieldText(); f.curPos > len(*text) {
+				f.curPos = len(*text)
+			}
+			if row == fieldSearch {
+				f.begin()
 			}
 			return true
 		}
 		if f.selected {
-			f.selected = false
+			f.accept()
 			return true
 		}
 	}
 	if f.selected && evt.Type == termbox.EventKey {
+		text := f.fieldText()
 		ch := evt.Ch
+		if evt.Mod&termbox.ModAlt != 0 {
+			switch ch {
+			case 'r':
+				f.Regex = !f.Regex
+			case 'c':
+				f.IgnoreCase = !f.IgnoreCase
+			case 'w':
+				f.WholeWord = !f.WholeWord
+			}
+			f.incremental()
+			return true
+		}
+		edited := false
 		switch evt.Key {
 		case termbox.KeyBackspace, termbox.KeyBackspace2:
 			if f.curPos > 0 {
-				f.searchString = f.searchString[:f.curPos-1] + f.searchString[f.curPos:]
+				*text = append((*text)[:f.curPos-1], (*text)[f.curPos:]...)
 				f.curPos--
+				edited = true
 			}
 		case termbox.KeyDelete:
-			if f.curPos < len(f.searchString) {
-				f.searchString = f.searchString[:f.curPos] + f.searchString[f.curPos+1:]
+			if f.curPos < len(*text) {
+				*text = append((*text)[:f.curPos], (*text)[f.curPos+1:]...)
+				edited = true
 			}
 		case termbox.KeyArrowLeft:
 			if f.curPos > 0 {
 				f.curPos--
 			}
 		case termbox.KeyArrowRight:
-			if f.curPos < len(f.searchString) {
+			if f.curPos < len(*text) {
 				f.curPos++
 			}
-		
+		case termbox.KeyTab, termbox.KeyArrowUp, termbox.KeyArrowDown:
+			f.field = 1 - f.field
+			text = f.fieldText()
+			if f.curPos > len(*text) {
+				f.curPos = len(*text)
+			}
+			if f.field == fieldSearch {
+				f.begin()
+			}
+			return true
+		case termbox.KeyCtrlA:
+			f.ReplaceAll()
+			return true
 		case termbox.KeySpace:
 			ch = ' '
+		case termbox.KeyEsc:
+			f.cancel()
+			return true
 		case termbox.KeyEnter:
-			f.Search(false)
-			f.selected = false
+			if f.field == fieldReplace {
+				f.ReplaceNext()
+				return true
+			}
+			f.accept()
+			return true
 		}
 		if ch != '\x00' {
-			f.searchString = f.searchString[:f.curPos] + string(ch) + f.searchString[f.curPos:]
+			*text = append((*text)[:f.curPos], append([]rune{ch}, (*text)[f.curPos:]...)...)
 			f.curPos++
+			edited = true
+		}
+		if edited && f.field == fieldSearch {
+			f.incremental()
+		} else {
+			f.update()
 		}
 		return true
 	}
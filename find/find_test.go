@@ -0,0 +1,149 @@
+package find
+
+import (
+	"testing"
+
+	"github.com/andyleap/editor/buffer"
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+func newPanel(text, search, replace string) (*FindPanel, *buffer.Buffer) {
+	b := buffer.New([]rune(text))
+	f := &FindPanel{Buf: b, Regex: true}
+	f.searchString = []rune(search)
+	f.replaceString = []rune(replace)
+	return f, b
+}
+
+func TestReplaceAll(t *testing.T) {
+	tests := []struct {
+		text, search, replace string
+		regex                 bool
+		want                  string
+	}{
+		{"foo(1, 2)\nfoo(3, 4)\n", `foo\((\d+), (\d+)\)`, "bar($2, $1)", true, "bar(2, 1)\nbar(4, 3)\n"},
+		{"ab ab", `(a)(b)`, "${2}x$1", true, "bxa bxa"},
+		{"key=value", `(?P<k>\w+)=(?P<v>\w+)`, "$v=$k", true, "value=key"},
+		// Without Regex the replacement is taken literally.
+		{"a.b a.b", ".", "$1", false, "a$1b a$1b"},
+		// Matches of zero width are replaced too.
+		{"a\nb\nc", "^", "// ", true, "// a\n// b\n// c"},
+		{"ab cd", `\b`, "|", true, "|ab| |cd|"},
+	}
+	for _, test := range tests {
+		f, b := newPanel(test.text, test.search, test.replace)
+		f.Regex = test.regex
+		f.ReplaceAll()
+		if got := b.Text(); got != test.want {
+			t.Errorf("replacing %q with %q: got %q, want %q", test.search, test.replace, got, test.want)
+		}
+		b.Undo()
+		if got := b.Text(); got != test.text {
+			t.Errorf("replacing %q with %q: undo left %q, want %q", test.search, test.replace, got, test.text)
+		}
+	}
+}
+
+func TestReplaceNext(t *testing.T) {
+	f, b := newPanel("x1 x2 x3", `x(\d)`, "y$1")
+	b.SetPos(2)
+	steps := []struct {
+		text string
+		pos  int
+	}{
+		// Not on a match, so it only moves to one.
+		{"x1 x2 x3", 3},
+		{"x1 y2 x3", 6},
+		// The next match wraps round to the first.
+		{"x1 y2 y3", 0},
+		// With none left the cursor stays after the replacement.
+		{"y1 y2 y3", 2},
+	}
+	for i, step := range steps {
+		f.ReplaceNext()
+		if b.Text() != step.text || b.Pos() != step.pos {
+			t.Errorf("step %d: got %q at %d, want %q at %d", i, b.Text(), b.Pos(), step.text, step.pos)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	f, b := newPanel("one two one two", "two", "")
+	f.Regex = false
+	var got []int
+	for l1 := 0; l1 < 3; l1++ {
+		f.Search(false)
+		got = append(got, b.Pos())
+	}
+	if want := []int{4, 12, 4}; got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("searching down visited %v, want %v", got, want)
+	}
+	if b.Sel != 7 {
+		t.Errorf("selection ends at %d, want 7", b.Sel)
+	}
+	got = got[:0]
+	for l1 := 0; l1 < 2; l1++ {
+		f.Search(true)
+		got = append(got, b.Pos())
+	}
+	if want := []int{12, 4}; got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("searching up visited %v, want %v", got, want)
+	}
+
+	// Matches of zero width are stepped through one at a time.
+	f, b = newPanel("a\nb\nc", "^", "")
+	got = got[:0]
+	for l1 := 0; l1 < 3; l1++ {
+		f.Search(false)
+		got = append(got, b.Pos())
+	}
+	if want := []int{2, 4, 0}; got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("searching for ^ visited %v, want %v", got, want)
+	}
+	if b.Sel != -1 {
+		t.Errorf("a zero width match selected up to %d", b.Sel)
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	r := core.Rect{W: 80, H: 24}
+	typeText := func(f *FindPanel, s string) {
+		for _, ch := range s {
+			f.Handle(r, termbox.Event{Type: termbox.EventKey, Ch: ch})
+		}
+	}
+
+	f, b := newPanel("one two one two", "", "")
+	f.Regex = false
+	b.SetPos(5)
+	f.Focus()
+	typeText(f, "o")
+	if b.Pos() != 6 {
+		t.Errorf("after o the cursor is at %d, want 6 on the o of two", b.Pos())
+	}
+	typeText(f, "n")
+	if b.Pos() != 8 || b.Sel != 10 {
+		t.Errorf("after on the cursor is at %d-%d, want 8-10", b.Pos(), b.Sel)
+	}
+	// Nothing matches, so the cursor goes back to where the search began.
+	typeText(f, "x")
+	if b.Pos() != 5 || b.Sel != -1 {
+		t.Errorf("with no match the cursor is at %d, %d, want 5, -1", b.Pos(), b.Sel)
+	}
+	f.Handle(r, termbox.Event{Type: termbox.EventKey, Key: termbox.KeyBackspace})
+	f.Handle(r, termbox.Event{Type: termbox.EventKey, Key: termbox.KeyEsc})
+	if b.Pos() != 5 || b.Sel != -1 {
+		t.Errorf("after Esc the cursor is at %d, %d, want 5, -1", b.Pos(), b.Sel)
+	}
+
+	// Past the last match, the search wraps round to the top.
+	f, b = newPanel("one two one two", "", "")
+	b.SetPos(13)
+	f.Focus()
+	typeText(f, "one")
+	f.Handle(r, termbox.Event{Type: termbox.EventKey, Key: termbox.KeyEnter})
+	if b.Pos() != 0 || b.Sel != 3 || f.selected {
+		t.Errorf("got %d-%d, selected %v, want 0-3 with the panel let go", b.Pos(), b.Sel, f.selected)
+	}
+}
@@ -0,0 +1,70 @@
+package find
+
+import (
+	"sort"
+
+	"github.com/andyleap/editor/buffer"
+	"github.com/andyleap/termbox-go"
+)
+
+// Highlighter is a buffer.Styler that paints every match of a FindPanel's
+// current search in its buffer.
+type Highlighter struct {
+	f *FindPanel
+	b *buffer.Buffer
+
+	// Visible reports whether highlights should be shown at all, normally
+	// whether the find panel is open.
+	Visible func() bool
+
+	matches []Match
+	gen     int
+	stale   bool
+}
+
+func NewHighlighter(f *FindPanel, b *buffer.Buffer, visible func() bool) *Highlighter {
+	return &Highlighter{
+		f:       f,
+		b:       b,
+		Visible: visible,
+		stale:   true,
+	}
+}
+
+func (h *Highlighter) refresh() {
+	if !h.stale && h.gen == h.f.gen {
+		return
+	}
+	h.matches = h.matches[:0]
+	if h.f.Buf == h.b {
+		h.f.update()
+		h.matches = append(h.matches, h.f.matches...)
+	}
+	h.gen = h.f.gen
+	h.stale = false
+}
+
+func (h *Highlighter) match(pos int) bool {
+	if h.Visible != nil && !h.Visible() {
+		return false
+	}
+	h.refresh()
+	i := sort.Search(len(h.matches), func(i int) bool { return h.matches[i].End > pos })
+	return i < len(h.matches) && h.matches[i].Start <= pos
+}
+
+func (h *Highlighter) Style(pos int, ifg, ibg termbox.Attribute) (fg, bg termbox.Attribute) {
+	// Leave the selection, which marks the current match, alone.
+	if ibg != termbox.ColorDefault || !h.match(pos) {
+		return ifg, ibg
+	}
+	return termbox.ColorBlack, termbox.ColorYellow
+}
+
+func (h *Highlighter) Kind(pos int) buffer.Kind { return buffer.KindNormal }
+
+func (h *Highlighter) Insert(pos int) { h.stale = true }
+
+func (h *Highlighter) Delete(pos int) { h.stale = true }
+
+func (h *Highlighter) Clear() { h.stale = true }
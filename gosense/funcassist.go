@@ -1,14 +1,10 @@
 package gosense
 
 import (
-	"encoding/json"
-	"fmt"
-	"os/exec"
-	"strings"
-
 	"github.com/andyleap/editor/buffer"
 	"github.com/andyleap/editor/core"
 	"github.com/andyleap/editor/golight"
+	"github.com/andyleap/editor/lsp"
 	"github.com/andyleap/termbox-go"
 )
 
@@ -16,6 +12,10 @@ type FuncAssist struct {
 	b         *buffer.Buffer
 	lastCheck int
 	lastFunc  string
+
+	// Post, if set, lets Render draw before the language server has
+	// answered with a signature.
+	Post core.PostFunc
 }
 
 func NewFuncAssist(b *buffer.Buffer) *FuncAssist {
@@ -75,16 +75,16 @@ func (fa *FuncAssist) getFuncPos() (funcPos, argNum int) {
 
 func (fa *FuncAssist) Render(r core.Rect) {
 	for l1 := r.X; l1 < r.X+r.W; l1++ {
-		termbox.SetCell(l1, r.Y, ' ', termbox.ColorWhite, termbox.ColorBlue)
+		core.SetCell(l1, r.Y, ' ', termbox.ColorWhite, termbox.ColorBlue)
 	}
 	f, arg := fa.getFuncPos()
 	if f != fa.lastCheck {
 		fa.lastCheck = f
+		fa.lastFunc = ""
 		if f == -1 {
-			fa.lastFunc = ""
 			return
 		}
-		fa.lastFunc = fa.getFunc(f)
+		fa.getFunc(f)
 	}
 	curArg := 0
 	level := 0
@@ -125,7 +125,7 @@ argLoop:
 				fg = termbox.ColorWhite | termbox.AttrBold
 			}
 		}
-		termbox.SetCell(r.X+i, r.Y, c, fg, bg)
+		core.SetCell(r.X+i, r.Y, c, fg, bg)
 	}
 }
 
@@ -133,37 +133,49 @@ func (fa *FuncAssist) Handle(r core.Rect, evt termbox.Event) bool {
 	return false
 }
 
-func (fa *FuncAssist) getFunc(f int) string {
-	cmd := exec.Command("gocode", "-f=json", "autocomplete", fa.b.Filename, fmt.Sprintf("c%d", f))
+// getFunc looks up the signature of the function called at f and stores it
+// in lastFunc, provided the cursor is still in the same call by then. Only
+// Go files are looked up.
+func (fa *FuncAssist) getFunc(f int) {
+	if !fa.b.IsGo() {
+		return
+	}
+	filename, rev, text := fa.b.Filename, fa.b.Rev(), fa.b.Text()
 
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return ""
+	apply := func(label string) {
+		if fa.lastCheck != f {
+			return
+		}
+		fa.lastFunc = label
+	}
+
+	if fa.Post == nil {
+		apply(signature(filename, rev, text, f))
+		return
 	}
 	go func() {
-		fa.b.GB.WriteTo(stdin)
-		stdin.Close()
+		label := signature(filename, rev, text, f)
+		fa.Post(func() { apply(label) })
 	}()
+}
 
-	out, _ := cmd.Output()
-
-	options := []Option{}
-	offset := 0
-
-	data := []interface{}{
-		&offset,
-		&options,
+// signature asks the workspace's language server for the signature of the
+// call whose opening parenthesis is at f.
+func signature(filename string, rev int, text string, f int) string {
+	c, err := lsp.ForFile(filename)
+	if err != nil {
+		return ""
 	}
-
-	json.Unmarshal(out, &data)
-	name := ""
-	for l1 := 0; l1 < offset; l1++ {
-		name = name + string(fa.b.GB.Get(f-offset+l1))
-	}
-	for _, option := range options {
-		if option.Name == name {
-			return option.Name + strings.TrimPrefix(option.Type, "func")
-		}
+	if err := c.Sync(filename, rev, text); err != nil {
+		return ""
+	}
+	help, err := c.SignatureHelp(filename, lsp.PositionOf([]rune(text), f+1))
+	if err != nil || len(help.Signatures) == 0 {
+		return ""
+	}
+	active := help.ActiveSignature
+	if active < 0 || active >= len(help.Signatures) {
+		active = 0
 	}
-	return ""
+	return help.Signatures[active].Label
 }
@@ -1,12 +1,11 @@
 package gosense
 
 import (
-	"encoding/json"
-	"fmt"
-	"os/exec"
+	"unicode"
 
 	"github.com/andyleap/editor/buffer"
 	"github.com/andyleap/editor/core"
+	"github.com/andyleap/editor/lsp"
 	"github.com/andyleap/termbox-go"
 )
 
@@ -22,12 +21,14 @@ type GoSense struct {
 	Options []Option
 	Pos     int
 	Offset  int
-	X, Y    int
 
 	Selected int
 	Scroll   int
 
 	Help string
+
+	// Post, if set, lets typing carry on while completions are looked up.
+	Post core.PostFunc
 }
 
 func New(b *buffer.Buffer) *GoSense {
@@ -38,7 +39,11 @@ func New(b *buffer.Buffer) *GoSense {
 
 func (gs *GoSense) Render(r core.Rect) {
 	if len(gs.Options) > 0 {
-		cX, cY := gs.X, gs.Y-gs.b.Scroll
+		x, y := gs.b.ScreenPos(r, gs.Pos-gs.Offset)
+		cX, cY := x-r.X, y-r.Y
+		if cX < 0 {
+			cX = 0
+		}
 		finalRect := core.Rect{r.X + cX, r.Y + (cY + 1), 120, r.H - (cY + 1)}
 		if finalRect.H > len(gs.Options) {
 			finalRect.H = len(gs.Options)
@@ -92,13 +97,11 @@ func (gs *GoSense) Handle(r core.Rect, evt termbox.Event) bool {
 			case termbox.KeyEsc:
 				gs.Options = nil
 			case termbox.KeyEnter, termbox.KeyTab, termbox.KeySpace:
-				pos := gs.b.Pos()
-				for l1 := gs.Offset; l1 > 0; l1-- {
-					gs.b.GB.Delete(pos)
-					pos--
-				}
-				gs.b.SetPos(pos)
-				gs.b.InsertString(gs.Options[gs.Selected].Name)
+				gs.b.BeginEdit()
+				gs.b.EachCursor(func() {
+					gs.complete(gs.Options[gs.Selected].Name)
+				})
+				gs.b.EndEdit()
 				gs.Options = nil
 				return true
 			case termbox.KeyArrowDown:
@@ -128,45 +131,76 @@ func (gs *GoSense) Handle(r core.Rect, evt termbox.Event) bool {
 	return false
 }
 
+// complete replaces the identifier being typed at the cursor with name.
+// Other cursors may have less of it typed than the one the options were
+// looked up at, so no more than the identifier there is replaced.
+func (gs *GoSense) complete(name string) {
+	pos := gs.b.Pos()
+	n := 0
+	for n < gs.Offset && pos-n > 0 && isIdent(gs.b.GB.Get(pos-n-1)) {
+		n++
+	}
+	gs.b.Remove(pos-n, n)
+	gs.b.SetPos(pos - n)
+	gs.b.InsertString(name)
+}
+
 func (gs *GoSense) getOptions() {
+	if !gs.b.IsGo() {
+		gs.Options = nil
+		return
+	}
 	gs.Pos = gs.b.Pos()
+	pos, rev := gs.Pos, gs.b.Rev()
+	filename, text := gs.b.Filename, gs.b.Text()
 
-	/*
-		wd, _ := os.Getwd()
-		filename := filepath.Join(wd, gs.b.Filename)
-
-		closest := ""
-		for _, p := range filepath.SplitList(os.Getenv("GOPATH")) {
-			rel, _ := filepath.Rel(filepath.Join(p, "src"), filename)
-			if closest == "" || len(rel) < len(closest) {
-				closest = rel
-			}
+	apply := func(offset int, options []Option) {
+		if gs.b.Rev() != rev || gs.b.Pos() != pos {
+			return
 		}
-	*/
-
-	cmd := exec.Command("gocode", "-f=json", "autocomplete", gs.b.Filename, fmt.Sprintf("c%d", gs.Pos))
+		gs.Offset, gs.Options = offset, options
+		gs.Selected = 0
+	}
 
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		gs.Options = gs.Options[:0]
+	if gs.Post == nil {
+		apply(autocomplete(filename, rev, text, pos))
 		return
 	}
 	go func() {
-		gs.b.GB.WriteTo(stdin)
-		stdin.Close()
+		offset, options := autocomplete(filename, rev, text, pos)
+		gs.Post(func() { apply(offset, options) })
 	}()
+}
 
-	out, _ := cmd.Output()
-
-	gs.Options = gs.Options[:0]
-
-	data := []interface{}{
-		&gs.Offset,
-		&gs.Options,
+// autocomplete asks the workspace's language server for the completions at
+// pos in text, returning how many runes before pos the completed identifier
+// starts.
+func autocomplete(filename string, rev int, text string, pos int) (offset int, options []Option) {
+	c, err := lsp.ForFile(filename)
+	if err != nil {
+		return 0, nil
+	}
+	if err := c.Sync(filename, rev, text); err != nil {
+		return 0, nil
 	}
+	runes := []rune(text)
+	for pos-offset > 0 && isIdent(runes[pos-offset-1]) {
+		offset++
+	}
+	items, err := c.Completion(filename, lsp.PositionOf(runes, pos))
+	if err != nil {
+		return 0, nil
+	}
+	for _, item := range items {
+		options = append(options, Option{
+			Class: lsp.CompletionKinds[item.Kind],
+			Name:  item.Label,
+			Type:  item.Detail,
+		})
+	}
+	return offset, options
+}
 
-	json.Unmarshal(out, &data)
-	gs.X, gs.Y = gs.b.GetCur(gs.b.Pos() - gs.Offset)
-	gs.Selected = 0
-	return
+func isIdent(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
 }
@@ -0,0 +1,56 @@
+package gosense
+
+import (
+	"testing"
+
+	"github.com/andyleap/editor/buffer"
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/editor/core/screentest"
+	"github.com/andyleap/termbox-go"
+)
+
+type layers []core.UI
+
+func (l layers) Render(r core.Rect) {
+	for _, ui := range l {
+		ui.Render(r)
+	}
+}
+
+func (l layers) Handle(r core.Rect, evt termbox.Event) bool { return false }
+
+func TestRender(t *testing.T) {
+	b := buffer.New([]rune("package main\n\nfunc main() {\n\tfmt.Pr\n}\n"))
+	b.SetPos(b.LineOffset(3) + 7)
+	gs := New(b)
+	gs.Pos, gs.Offset = b.Pos(), 2
+	gs.Options = []Option{
+		{Class: "func", Name: "Print", Type: "func(a ...any) (n int, err error)"},
+		{Class: "func", Name: "Printf", Type: "func(format string, a ...any) (n int, err error)"},
+		{Class: "func", Name: "Println", Type: "func(a ...any) (n int, err error)"},
+	}
+	gs.Selected = 1
+	m := screentest.Render(layers{b, gs}, 80, 10)
+	screentest.Golden(t, "popup", m.String()+"\n"+screentest.Highlights(m))
+}
+
+func TestOnlyGoFiles(t *testing.T) {
+	b := buffer.New([]rune("# Notes\n\nfmt.\n"))
+	b.Filename = "notes.md"
+	b.SetPos(b.LineOffset(2) + 4)
+	gs := New(b)
+	gs.Post = func(func()) { t.Error("looked up completions in a Markdown file") }
+	gs.Options = []Option{{Name: "stale"}}
+	gs.Handle(core.Rect{W: 80, H: 10}, termbox.Event{Type: termbox.EventKey, Key: termbox.KeyCtrlSpace})
+	if gs.Options != nil {
+		t.Errorf("got options %v, want none", gs.Options)
+	}
+
+	fa := NewFuncAssist(b)
+	fa.Post = func(func()) { t.Error("looked up a signature in a Markdown file") }
+	b.InsertString("Println(")
+	fa.Render(core.Rect{W: 80, H: 1})
+	if fa.lastFunc != "" {
+		t.Errorf("got signature %q, want none", fa.lastFunc)
+	}
+}
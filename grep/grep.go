@@ -0,0 +1,143 @@
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+const maxResults = 10000
+
+// Result is a single matching line.
+type Result struct {
+	File string
+	// Line and Col locate the start of the match, counting from zero, with
+	// Col in runes.
+	Line, Col int
+	Text      string
+}
+
+type Options struct {
+	Pattern    string
+	Regex      bool
+	IgnoreCase bool
+	SkipVendor bool
+}
+
+func (o Options) compile() (*regexp.Regexp, error) {
+	expr := o.Pattern
+	if !o.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if o.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// Search looks for the pattern in every file under root, skipping .git,
+// anything matched by a .gitignore and, optionally, vendor directories.
+// Files are searched concurrently; results are sorted by file and line.
+func Search(root string, opts Options) ([]Result, error) {
+	re, err := opts.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(chan string)
+	go func() {
+		defer close(files)
+		walk(root, opts, files)
+	}()
+
+	var mu sync.Mutex
+	var results []Result
+	var wg sync.WaitGroup
+	for l1 := 0; l1 < runtime.NumCPU(); l1++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range files {
+				found := searchFile(re, file)
+				mu.Lock()
+				if len(results) < maxResults {
+					results = append(results, found...)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].File != results[j].File {
+			return results[i].File < results[j].File
+		}
+		if results[i].Line != results[j].Line {
+			return results[i].Line < results[j].Line
+		}
+		return results[i].Col < results[j].Col
+	})
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results, nil
+}
+
+func walk(root string, opts Options, files chan<- string) {
+	ig := newIgnorer()
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != root {
+				name := info.Name()
+				if name == ".git" || (opts.SkipVendor && name == "vendor") || ig.ignored(root, path, true) {
+					return filepath.SkipDir
+				}
+			}
+			ig.load(path)
+			return nil
+		}
+		if !info.Mode().IsRegular() || ig.ignored(root, path, false) {
+			return nil
+		}
+		files <- path
+		return nil
+	})
+}
+
+func searchFile(re *regexp.Regexp, file string) []Result {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var results []Result
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 0; scanner.Scan(); line++ {
+		text := scanner.Bytes()
+		if bytes.IndexByte(text, 0) >= 0 {
+			// Binary file.
+			return nil
+		}
+		for _, m := range re.FindAllIndex(text, -1) {
+			results = append(results, Result{
+				File: file,
+				Line: line,
+				Col:  utf8.RuneCount(text[:m[0]]),
+				Text: string(text),
+			})
+		}
+	}
+	return results
+}
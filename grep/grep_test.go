@@ -0,0 +1,73 @@
+package grep
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":           "*.log\n!keep.log\nbuild/\n",
+		"a.txt":                "match\nno\nmatch and match\n",
+		"a.log":                "match\n",
+		"keep.log":             "match\n",
+		"build/out.txt":        "match\n",
+		"sub/.gitignore":       "!debug.log\n/local.txt\n",
+		"sub/debug.log":        "match\n",
+		"sub/local.txt":        "match\n",
+		"sub/deeper/local.txt": "match\n",
+		"vendor/v.txt":         "match\n",
+		".git/config":          "match\n",
+		"wide.txt":             "héllo match\n",
+		"binary.bin":           "match\x00\n",
+	}
+	for name, text := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Search(root, Options{Pattern: "match", SkipVendor: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type hit struct {
+		file      string
+		line, col int
+	}
+	var got []hit
+	for _, r := range results {
+		rel, _ := filepath.Rel(root, r.File)
+		got = append(got, hit{filepath.ToSlash(rel), r.Line, r.Col})
+	}
+	want := []hit{
+		{"a.txt", 0, 0},
+		{"a.txt", 2, 0},
+		{"a.txt", 2, 10},
+		{"keep.log", 0, 0},
+		{"sub/debug.log", 0, 0},
+		{"sub/deeper/local.txt", 0, 0},
+		// Columns count runes, not bytes.
+		{"wide.txt", 0, 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+	if len(results) > 0 && results[0].Text != "match" {
+		t.Errorf("first result's text is %q, want the whole line", results[0].Text)
+	}
+
+	results, _ = Search(root, Options{Pattern: "MATCH", IgnoreCase: true})
+	if len(results) != len(want)+1 {
+		t.Errorf("ignoring case and searching vendor found %d, want %d", len(results), len(want)+1)
+	}
+	if _, err := Search(root, Options{Pattern: "(", Regex: true}); err == nil {
+		t.Error("a bad regexp gave no error")
+	}
+}
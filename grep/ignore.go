@@ -0,0 +1,132 @@
+package grep
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// rule is one line of a .gitignore file.
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignorer holds the .gitignore rules found so far, keyed by the directory
+// whose .gitignore they came from.
+type ignorer struct {
+	rules map[string][]rule
+}
+
+func newIgnorer() *ignorer {
+	return &ignorer{rules: map[string][]rule{}}
+}
+
+// load reads dir/.gitignore, if there is one.
+func (ig *ignorer) load(dir string) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	var rules []rule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if r, ok := parseRule(scanner.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	ig.rules[dir] = rules
+}
+
+func parseRule(line string) (rule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+	r := rule{}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	// Patterns without an inner slash match a name at any depth; the rest
+	// are relative to the directory holding the .gitignore.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := ""
+	for i := 0; i < len(line); i++ {
+		switch {
+		case strings.HasPrefix(line[i:], "**/"):
+			expr += "(?:.*/)?"
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			expr += ".*"
+			i++
+		case line[i] == '*':
+			expr += "[^/]*"
+		case line[i] == '?':
+			expr += "[^/]"
+		case line[i] == '\\' && i+1 < len(line):
+			i++
+			expr += regexp.QuoteMeta(line[i : i+1])
+		case line[i] == '[' && strings.IndexByte(line[i+1:], ']') > 0:
+			end := i + 1 + strings.IndexByte(line[i+1:], ']')
+			class := line[i+1 : end]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			expr += "[" + class + "]"
+			i = end
+		default:
+			expr += regexp.QuoteMeta(line[i : i+1])
+		}
+	}
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+// ignored reports whether path, found under root, is excluded by any
+// .gitignore between root and it.
+func (ig *ignorer) ignored(root, path string, isDir bool) bool {
+	ignored := false
+	dir := filepath.Dir(path)
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		if dir == root || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	for l1 := len(dirs) - 1; l1 >= 0; l1-- {
+		rel, err := filepath.Rel(dirs[l1], path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, r := range ig.rules[dirs[l1]] {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.re.MatchString(rel) {
+				ignored = !r.negate
+			}
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,65 @@
+package grep
+
+import "testing"
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		// Without an inner slash a pattern matches at any depth.
+		{"*.log", "a.log", false, true},
+		{"*.log", "x/y/a.log", false, true},
+		{"*.log", "a.log.txt", false, false},
+		{"foo?", "food", false, true},
+		{"foo?", "foo/", false, false},
+
+		// A leading or inner slash anchors it to the .gitignore's directory.
+		{"/build", "build", true, true},
+		{"/build", "x/build", true, false},
+		{"doc/*.txt", "doc/a.txt", false, true},
+		{"doc/*.txt", "x/doc/a.txt", false, false},
+		{"doc/*.txt", "doc/x/a.txt", false, false},
+		{"**/foo", "a/b/foo", false, true},
+		{"**/foo", "foo", false, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"logs/**", "logs/x/y.txt", false, true},
+
+		// A trailing slash matches only directories.
+		{"logs/", "logs", true, true},
+		{"logs/", "logs", false, false},
+		{"logs/", "x/logs", true, true},
+
+		{"[abc].txt", "b.txt", false, true},
+		{"[abc].txt", "d.txt", false, false},
+		{"[!abc].txt", "d.txt", false, true},
+		{"[a-c].txt", "c.txt", false, true},
+		{`\#notes`, "#notes", false, true},
+		{`\!keep`, "!keep", false, true},
+		{"a.txt  ", "a.txt", false, true},
+		{"a.b", "axb", false, false},
+	}
+	for _, test := range tests {
+		r, ok := parseRule(test.pattern)
+		if !ok {
+			t.Errorf("%q wasn't taken as a rule", test.pattern)
+			continue
+		}
+		got := (!r.dirOnly || test.isDir) && r.re.MatchString(test.path)
+		if got != test.want {
+			t.Errorf("%q matching %q (dir %v) = %v, want %v", test.pattern, test.path, test.isDir, got, test.want)
+		}
+	}
+
+	for _, line := range []string{"", "   ", "# comment"} {
+		if _, ok := parseRule(line); ok {
+			t.Errorf("%q was taken as a rule", line)
+		}
+	}
+	if r, _ := parseRule("!keep.log"); !r.negate || !r.re.MatchString("keep.log") {
+		t.Errorf("!keep.log should negate and match keep.log")
+	}
+}
@@ -0,0 +1,287 @@
+package grep
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+// Panel is a "Find in Files" dialog: a query line, its options and a
+// scrollable list of results.
+type Panel struct {
+	root    string
+	query   []rune
+	curPos  int
+	Options Options
+
+	results []Result
+	err     error
+	status  string
+
+	scroll       int
+	selected     int
+	lastSelected int
+	doubleClick  time.Time
+
+	// Open is called with the result the user picked.
+	Open func(r Result)
+	// Close is called when the panel is dismissed.
+	Close func()
+	// Post, if set, lets the panel stay responsive while a search walks
+	// the tree.
+	Post core.PostFunc
+
+	gen int
+}
+
+func NewPanel(root string) *Panel {
+	return &Panel{
+		root:         root,
+		Options:      Options{SkipVendor: true},
+		lastSelected: -1,
+	}
+}
+
+func (p *Panel) area(r core.Rect) core.Rect {
+	return r.Shrink(5, 3)
+}
+
+type option struct {
+	label string
+	on    *bool
+}
+
+func (p *Panel) options() []option {
+	return []option{
+		{"Regex", &p.Options.Regex},
+		{"Ignore case", &p.Options.IgnoreCase},
+		{"Skip vendor", &p.Options.SkipVendor},
+	}
+}
+
+func (p *Panel) listHeight(r core.Rect) int {
+	return r.H - 5
+}
+
+// Search runs the query and replaces the result list.
+func (p *Panel) Search() {
+	p.Options.Pattern = string(p.query)
+	p.results, p.err = nil, nil
+	p.scroll, p.selected = 0, 0
+	if p.Options.Pattern == "" {
+		p.status = ""
+		return
+	}
+	p.gen++
+	gen, opts := p.gen, p.Options
+	apply := func(results []Result, err error) {
+		if gen != p.gen {
+			return
+		}
+		p.results, p.err = results, err
+		if err != nil {
+			p.status = err.Error()
+			return
+		}
+		p.status = strconv.Itoa(len(results)) + " results"
+	}
+	if p.Post == nil {
+		apply(Search(p.root, opts))
+		return
+	}
+	p.status = "searching..."
+	go func() {
+		results, err := Search(p.root, opts)
+		p.Post(func() { apply(results, err) })
+	}()
+}
+
+// rerun repeats the last search after its options change.
+func (p *Panel) rerun() {
+	if p.Options.Pattern != "" {
+		p.Search()
+	}
+}
+
+func (p *Panel) label(res Result) string {
+	name := res.File
+	if rel, err := filepath.Rel(p.root, res.File); err == nil {
+		name = rel
+	}
+	return name + ":" + strconv.Itoa(res.Line+1) + ":" + strconv.Itoa(res.Col+1) + ": " + strings.TrimSpace(res.Text)
+}
+
+func (p *Panel) Render(r core.Rect) {
+	r = p.area(r)
+
+	core.Frame(r, termbox.ColorWhite, termbox.ColorBlue)
+	core.RenderString(r.X+2, r.Y, " Find in Files ", termbox.ColorWhite|termbox.AttrBold, termbox.ColorBlue)
+
+	core.RenderString(r.X+1, r.Y+1, "Find: ", termbox.ColorWhite, termbox.ColorBlue)
+	for l1 := r.X + 7; l1 < r.X+r.W-1; l1++ {
+		core.SetCell(l1, r.Y+1, ' ', termbox.ColorBlack, termbox.ColorWhite)
+	}
+	core.RenderString(r.X+7, r.Y+1, string(p.query), termbox.ColorBlack, termbox.ColorWhite)
+	core.SetCursor(r.X+7+p.curPos, r.Y+1)
+
+	x := r.X + 1
+	for _, o := range p.options() {
+		box := "[ ] "
+		if *o.on {
+			box = "[x] "
+		}
+		core.RenderString(x, r.Y+2, box+o.label, termbox.ColorWhite, termbox.ColorBlue)
+		x += len(box) + len(o.label) + 2
+	}
+	core.RenderString(r.X+r.W-1-len(p.status), r.Y+2, p.status, termbox.ColorWhite, termbox.ColorBlue)
+
+	h := p.listHeight(r)
+	if p.scroll > p.selected {
+		p.scroll = p.selected
+	}
+	if p.scroll < p.selected-(h-1) {
+		p.scroll = p.selected - (h - 1)
+	}
+	for i := 0; i < h && p.scroll+i < len(p.results); i++ {
+		fg, bg := termbox.ColorWhite, termbox.ColorBlue
+		if p.scroll+i == p.selected {
+			fg, bg = termbox.ColorBlue, termbox.ColorWhite
+		}
+		line := []rune(p.label(p.results[p.scroll+i]))
+		for l1 := 0; l1 < r.W-2; l1++ {
+			ch := ' '
+			if l1 < len(line) {
+				ch = line[l1]
+				if ch == '\t' {
+					ch = ' '
+				}
+			}
+			core.SetCell(r.X+1+l1, r.Y+4+i, ch, fg, bg)
+		}
+	}
+}
+
+func (p *Panel) open() {
+	if p.selected >= 0 && p.selected < len(p.results) && p.Open != nil {
+		p.Open(p.results[p.selected])
+	}
+}
+
+func (p *Panel) move(d int) {
+	p.selected += d
+	if p.selected >= len(p.results) {
+		p.selected = len(p.results) - 1
+	}
+	if p.selected < 0 {
+		p.selected = 0
+	}
+}
+
+func (p *Panel) Handle(r core.Rect, evt termbox.Event) bool {
+	r = p.area(r)
+
+	if evt.Type == termbox.EventMouse {
+		switch evt.Key {
+		case termbox.MouseWheelUp:
+			p.move(-3)
+		case termbox.MouseWheelDown:
+			p.move(3)
+		case termbox.MouseLeft:
+			if evt.MouseY == r.Y+2 {
+				x := r.X + 1
+				for _, o := range p.options() {
+					w := len(o.label) + 4
+					if evt.MouseX >= x && evt.MouseX < x+w {
+						*o.on = !*o.on
+						p.rerun()
+					}
+					x += w + 2
+				}
+			}
+			i := evt.MouseY - (r.Y + 4)
+			if i >= 0 && i < p.listHeight(r) && p.scroll+i < len(p.results) {
+				p.selected = p.scroll + i
+				if p.lastSelected == p.selected && p.doubleClick.After(time.Now()) {
+					p.open()
+				}
+				p.lastSelected = p.selected
+				p.doubleClick = time.Now().Add(time.Millisecond * 500)
+			}
+		}
+		return true
+	}
+	if evt.Type != termbox.EventKey {
+		return true
+	}
+	ch := evt.Ch
+	if evt.Mod&termbox.ModAlt != 0 {
+		switch ch {
+		case 'r':
+			p.Options.Regex = !p.Options.Regex
+		case 'c':
+			p.Options.IgnoreCase = !p.Options.IgnoreCase
+		case 'v':
+			p.Options.SkipVendor = !p.Options.SkipVendor
+		}
+		p.rerun()
+		return true
+	}
+	switch evt.Key {
+	case termbox.KeyEsc:
+		if p.Close != nil {
+			p.Close()
+		}
+		return true
+	case termbox.KeyEnter:
+		if string(p.query) != p.Options.Pattern || p.results == nil {
+			p.Search()
+		} else {
+			p.open()
+		}
+		return true
+	case termbox.KeyArrowUp:
+		p.move(-1)
+		return true
+	case termbox.KeyArrowDown:
+		p.move(1)
+		return true
+	case termbox.KeyPgup:
+		p.move(-p.listHeight(r))
+		return true
+	case termbox.KeyPgdn:
+		p.move(p.listHeight(r))
+		return true
+	case termbox.KeyArrowLeft:
+		if p.curPos > 0 {
+			p.curPos--
+		}
+		return true
+	case termbox.KeyArrowRight:
+		if p.curPos < len(p.query) {
+			p.curPos++
+		}
+		return true
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if p.curPos > 0 {
+			p.query = append(p.query[:p.curPos-1], p.query[p.curPos:]...)
+			p.curPos--
+		}
+		return true
+	case termbox.KeyDelete:
+		if p.curPos < len(p.query) {
+			p.query = append(p.query[:p.curPos], p.query[p.curPos+1:]...)
+		}
+		return true
+	case termbox.KeySpace:
+		ch = ' '
+	}
+	if ch != '\x00' {
+		p.query = append(p.query[:p.curPos], append([]rune{ch}, p.query[p.curPos:]...)...)
+		p.curPos++
+	}
+	return true
+}
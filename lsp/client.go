@@ -0,0 +1,530 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command is the language server started for each workspace.
+var Command = []string{"gopls"}
+
+const callTimeout = 10 * time.Second
+
+var ErrClosed = errors.New("lsp: connection closed")
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return "lsp: " + e.Message
+}
+
+type document struct {
+	version int
+	rev     int
+}
+
+// Client is a connection to a language server over its stdin and stdout.
+// It is safe for concurrent use.
+type Client struct {
+	Root string
+
+	cmd *exec.Cmd
+	w   io.WriteCloser
+	r   *bufio.Reader
+
+	mu       sync.Mutex
+	nextID   int
+	pending  map[int]chan *message
+	handlers map[string]func(json.RawMessage)
+	closed   bool
+	closing  sync.Once
+	done     chan struct{}
+
+	docMu sync.Mutex
+	docs  map[string]*document
+}
+
+// Start runs the language server command for the workspace at root and
+// performs the initialize handshake.
+func Start(root string, command ...string) (*Client, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = root
+	w, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	r, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	c := &Client{
+		Root:     root,
+		cmd:      cmd,
+		w:        w,
+		r:        bufio.NewReader(r),
+		pending:  map[int]chan *message{},
+		handlers: map[string]func(json.RawMessage){},
+		docs:     map[string]*document{},
+		done:     make(chan struct{}),
+	}
+	go c.read()
+
+	params := InitializeParams{
+		ProcessID:    os.Getpid(),
+		RootURI:      URI(root),
+		Capabilities: map[string]interface{}{},
+	}
+	if err := c.Call("initialize", params, nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := c.Notify("initialized", struct{}{}); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// A workspace is the server started for a workspace root, or being
+// started for it.
+type workspace struct {
+	ready  chan struct{} // closed once Start has returned
+	client *Client
+	err    error
+}
+
+// failed reports whether the server failed to start or has since stopped.
+// One still starting has not failed.
+func (ws *workspace) failed() bool {
+	select {
+	case <-ws.ready:
+		return ws.err != nil || ws.client.isClosed()
+	default:
+		return false
+	}
+}
+
+// started returns the client if the server is up, without waiting for one
+// that is still starting.
+func (ws *workspace) started() (*Client, bool) {
+	if ws == nil || ws.failed() {
+		return nil, false
+	}
+	select {
+	case <-ws.ready:
+		return ws.client, true
+	default:
+		return nil, false
+	}
+}
+
+var (
+	workspacesMu sync.Mutex
+	workspaces   = map[string]*workspace{}
+)
+
+// ForFile returns the client for the workspace containing filename,
+// starting a server for it the first time it is asked for. Callers asking
+// while it starts wait for it; the lock is not held meanwhile, so CloseFile
+// and Shutdown never do.
+func ForFile(filename string) (*Client, error) {
+	if filename == "" {
+		return nil, errors.New("lsp: buffer has no file name")
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	root := WorkspaceRoot(abs)
+
+	workspacesMu.Lock()
+	ws, ok := workspaces[root]
+	if ok && !ws.failed() {
+		workspacesMu.Unlock()
+		<-ws.ready
+		return ws.client, ws.err
+	}
+	ws = &workspace{ready: make(chan struct{})}
+	workspaces[root] = ws
+	workspacesMu.Unlock()
+
+	ws.client, ws.err = Start(root, Command...)
+	close(ws.ready)
+	return ws.client, ws.err
+}
+
+// CloseFile tells the server for filename's workspace, if one is running,
+// that the file is no longer open in the editor. The next Sync of it opens
+// it afresh, so a new buffer for the same file isn't taken for an old
+// revision of the one closed.
+func CloseFile(filename string) {
+	if filename == "" {
+		return
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return
+	}
+	workspacesMu.Lock()
+	ws := workspaces[WorkspaceRoot(abs)]
+	workspacesMu.Unlock()
+	if c, ok := ws.started(); ok {
+		c.CloseFile(abs)
+	}
+}
+
+// Shutdown closes every client started by ForFile, including those still
+// starting once they have. It doesn't wait for the servers to stop.
+func Shutdown() {
+	workspacesMu.Lock()
+	all := workspaces
+	workspaces = map[string]*workspace{}
+	workspacesMu.Unlock()
+	for _, ws := range all {
+		go func(ws *workspace) {
+			<-ws.ready
+			if ws.err == nil {
+				ws.client.Close()
+			}
+		}(ws)
+	}
+}
+
+// WorkspaceRoot returns the nearest directory above filename holding a
+// go.mod, or the directory of filename if there is none.
+func WorkspaceRoot(filename string) string {
+	dir := filepath.Dir(filename)
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d
+		}
+		if filepath.Dir(d) == d {
+			return dir
+		}
+	}
+}
+
+// URI converts a file name into a file:// URI.
+func URI(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		abs = filename
+	}
+	abs = filepath.ToSlash(abs)
+	if !strings.HasPrefix(abs, "/") {
+		abs = "/" + abs
+	}
+	return "file://" + abs
+}
+
+// Filename converts a file:// URI back into a file name.
+func Filename(uri string) string {
+	name := strings.TrimPrefix(uri, "file://")
+	if len(name) > 1 && filepath.VolumeName(name[1:]) != "" {
+		name = name[1:]
+	}
+	return filepath.FromSlash(name)
+}
+
+func (c *Client) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// Handle registers fn to be called, on the client's reader goroutine, for
+// each notification of method from the server.
+func (c *Client) Handle(method string, fn func(params json.RawMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[method] = fn
+}
+
+func (c *Client) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(data)
+	return err
+}
+
+// Call sends a request and decodes its result into result, which may be
+// nil.
+func (c *Client) Call(method string, params, result interface{}) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	raw := json.RawMessage(strconv.Itoa(id))
+	if err := c.write(&message{ID: &raw, Method: method, Params: p}); err != nil {
+		return err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return ErrClosed
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-time.After(callTimeout):
+		return fmt.Errorf("lsp: %s timed out", method)
+	}
+}
+
+// Notify sends a notification, which has no response.
+func (c *Client) Notify(method string, params interface{}) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{Method: method, Params: p})
+}
+
+func (c *Client) read() {
+	defer c.shutdown()
+	for {
+		length := 0
+		for {
+			line, err := c.r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				break
+			}
+			if strings.HasPrefix(strings.ToLower(line), "content-length:") {
+				length, _ = strconv.Atoi(strings.TrimSpace(line[len("content-length:"):]))
+			}
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return
+		}
+		msg := &message{}
+		if err := json.Unmarshal(data, msg); err != nil {
+			continue
+		}
+		c.dispatch(msg)
+	}
+}
+
+func (c *Client) dispatch(msg *message) {
+	switch {
+	case msg.ID != nil && msg.Method == "":
+		id, err := strconv.Atoi(string(*msg.ID))
+		if err != nil {
+			return
+		}
+		// Sent holding the lock so that shutdown can't close ch first. It
+		// has room for the one response expected; any more are dropped.
+		c.mu.Lock()
+		select {
+		case c.pending[id] <- msg:
+		default:
+		}
+		c.mu.Unlock()
+	case msg.ID != nil:
+		// The server is asking us something. We have no configuration to
+		// offer and want no dynamic registrations, so empty answers do.
+		var result interface{}
+		if msg.Method == "workspace/configuration" {
+			var params struct {
+				Items []json.RawMessage `json:"items"`
+			}
+			json.Unmarshal(msg.Params, &params)
+			result = make([]interface{}, len(params.Items))
+		}
+		r, _ := json.Marshal(result)
+		c.write(&message{ID: msg.ID, Result: r})
+	default:
+		c.mu.Lock()
+		fn := c.handlers[msg.Method]
+		c.mu.Unlock()
+		if fn != nil {
+			fn(msg.Params)
+		}
+	}
+}
+
+func (c *Client) shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.w.Close()
+	close(c.done)
+}
+
+// Close asks the server to shut down and stops it. The exchange with the
+// server goes on in the background, so Close returns at once. It is safe
+// to call more than once.
+func (c *Client) Close() {
+	c.closing.Do(func() {
+		go func() {
+			if !c.isClosed() {
+				c.Call("shutdown", nil, nil)
+				c.Notify("exit", nil)
+			}
+			c.shutdown()
+			c.cmd.Wait()
+		}()
+	})
+}
+
+// Sync brings the server's copy of filename up to date with text, which is
+// revision rev of the buffer. Older revisions than the server already has
+// are ignored.
+func (c *Client) Sync(filename string, rev int, text string) error {
+	uri := URI(filename)
+	c.docMu.Lock()
+	defer c.docMu.Unlock()
+	doc, ok := c.docs[uri]
+	if !ok {
+		doc = &document{version: 1, rev: rev}
+		c.docs[uri] = doc
+		return c.Notify("textDocument/didOpen", DidOpenTextDocumentParams{
+			TextDocument: TextDocumentItem{
+				URI:        uri,
+				LanguageID: "go",
+				Version:    doc.version,
+				Text:       text,
+			},
+		})
+	}
+	if rev <= doc.rev {
+		return nil
+	}
+	doc.version++
+	doc.rev = rev
+	return c.Notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: doc.version},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: text}},
+	})
+}
+
+// CloseFile tells the server filename is no longer open.
+func (c *Client) CloseFile(filename string) error {
+	uri := URI(filename)
+	c.docMu.Lock()
+	defer c.docMu.Unlock()
+	if _, ok := c.docs[uri]; !ok {
+		return nil
+	}
+	delete(c.docs, uri)
+	return c.Notify("textDocument/didClose", DidCloseTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+}
+
+func positionParams(filename string, pos Position) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: URI(filename)},
+		Position:     pos,
+	}
+}
+
+func (c *Client) Completion(filename string, pos Position) ([]CompletionItem, error) {
+	var raw json.RawMessage
+	if err := c.Call("textDocument/completion", positionParams(filename, pos), &raw); err != nil {
+		return nil, err
+	}
+	var list CompletionList
+	if err := json.Unmarshal(raw, &list); err == nil && list.Items != nil {
+		return list.Items, nil
+	}
+	var items []CompletionItem
+	json.Unmarshal(raw, &items)
+	return items, nil
+}
+
+func (c *Client) SignatureHelp(filename string, pos Position) (*SignatureHelp, error) {
+	help := &SignatureHelp{}
+	if err := c.Call("textDocument/signatureHelp", positionParams(filename, pos), help); err != nil {
+		return nil, err
+	}
+	return help, nil
+}
+
+// Definition returns where the identifier at pos is declared.
+func (c *Client) Definition(filename string, pos Position) ([]Location, error) {
+	var raw json.RawMessage
+	if err := c.Call("textDocument/definition", positionParams(filename, pos), &raw); err != nil {
+		return nil, err
+	}
+	var loc Location
+	if err := json.Unmarshal(raw, &loc); err == nil && loc.URI != "" {
+		return []Location{loc}, nil
+	}
+	var locs []Location
+	json.Unmarshal(raw, &locs)
+	if len(locs) > 0 && locs[0].URI != "" {
+		return locs, nil
+	}
+	var links []LocationLink
+	json.Unmarshal(raw, &links)
+	locs = locs[:0]
+	for _, l := range links {
+		locs = append(locs, Location{URI: l.TargetURI, Range: l.TargetSelectionRange})
+	}
+	return locs, nil
+}
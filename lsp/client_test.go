@@ -0,0 +1,204 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func start(t *testing.T) (*Client, string) {
+	t.Helper()
+	root := t.TempDir()
+	c, err := Start(root, Command...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c, filepath.Join(root, "main.go")
+}
+
+func state(t *testing.T, c *Client) fakeState {
+	t.Helper()
+	var s fakeState
+	if err := c.Call("fake/state", struct{}{}, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestInitialize(t *testing.T) {
+	c, _ := start(t)
+	s := state(t, c)
+	if len(s.Methods) < 2 || s.Methods[0] != "initialize" || s.Methods[1] != "initialized" {
+		t.Errorf("server was sent %v, want initialize then initialized", s.Methods)
+	}
+	if string(s.ConfigReply) != "[null,null]" {
+		t.Errorf("workspace/configuration answered with %s, want [null,null]", s.ConfigReply)
+	}
+}
+
+func TestSync(t *testing.T) {
+	c, file := start(t)
+	uri := URI(file)
+	steps := []struct {
+		rev     int
+		text    string
+		version int
+		want    string
+	}{
+		{1, "package main\n", 1, "package main\n"},
+		{3, "package main\n\nfunc main() {}\n", 2, "package main\n\nfunc main() {}\n"},
+		// An older revision, say from a slow background lookup, is ignored.
+		{2, "package stale\n", 2, "package main\n\nfunc main() {}\n"},
+		{4, "package main\n// done\n", 3, "package main\n// done\n"},
+	}
+	for _, step := range steps {
+		if err := c.Sync(file, step.rev, step.text); err != nil {
+			t.Fatal(err)
+		}
+		doc := state(t, c).Docs[uri]
+		if doc == nil {
+			t.Fatalf("rev %d: server has no copy of %s", step.rev, uri)
+		}
+		if doc.Version != step.version || doc.Text != step.want {
+			t.Errorf("rev %d: server has version %d %q, want %d %q", step.rev, doc.Version, doc.Text, step.version, step.want)
+		}
+	}
+}
+
+func TestCloseFile(t *testing.T) {
+	c, file := start(t)
+	c.Sync(file, 10, "old buffer")
+	if err := c.CloseFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state(t, c).Docs[URI(file)]; ok {
+		t.Fatal("server still has the file open")
+	}
+	// A new buffer for the file starts its revisions again.
+	c.Sync(file, 1, "new buffer")
+	if doc := state(t, c).Docs[URI(file)]; doc == nil || doc.Text != "new buffer" {
+		t.Errorf("server has %+v, want the new buffer's text", doc)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c, file := start(t)
+	src := "package main\n\nfunc main() {\n\tfmt.Pri\n\tlistIt\n}\n"
+	text := []rune(src)
+	c.Sync(file, 1, string(text))
+	c.Sync(file, 2, string(text))
+	c.Sync(file, 3, string(text))
+
+	items, err := c.Completion(file, PositionOf(text, strings.Index(src, "Pri")+3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Label != "PriCompleted" || items[0].Detail != "v3" {
+		t.Errorf("got %+v, want PriCompleted from version 3", items)
+	}
+
+	// Servers may answer with a bare array of items too.
+	items, err = c.Completion(file, PositionOf(text, strings.Index(src, "listIt")+6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Label != "listItCompleted" {
+		t.Errorf("got %+v, want listItCompleted", items)
+	}
+}
+
+func TestSignatureHelp(t *testing.T) {
+	c, file := start(t)
+	// The emoji is two UTF-16 code units, so the position sent has to
+	// count it as such for the server to find the right place.
+	text := []rune("package main\n\nvar s = \"😀\"; fmt.Println(s, x)\n")
+	c.Sync(file, 1, string(text))
+	pos := len([]rune("package main\n\nvar s = \"😀\"; fmt.Println(s,"))
+	help, err := c.SignatureHelp(file, PositionOf(text, pos))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "var s = \"😀\"; fmt.Println(s,"
+	if len(help.Signatures) != 1 || help.Signatures[0].Label != want {
+		t.Errorf("got %+v, want the signature %q", help.Signatures, want)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	c, file := start(t)
+	c.Sync(file, 1, "package main\n")
+	locs, err := c.Definition(file, Position{Line: 0, Character: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locs) != 1 || Filename(locs[0].URI) != file || locs[0].Range.Start.Character != 1 {
+		t.Errorf("got %+v, want %s at 0:1", locs, file)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	c, _ := start(t)
+	err := c.Call("fake/nothing", struct{}{}, nil)
+	if _, ok := err.(*ResponseError); !ok {
+		t.Errorf("got %v, want a ResponseError", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	c, _ := start(t)
+	c.Close()
+	<-c.done
+	if err := c.Call("fake/state", struct{}{}, nil); err != ErrClosed {
+		t.Errorf("after Close got %v, want ErrClosed", err)
+	}
+}
+
+func TestCloseDuringCalls(t *testing.T) {
+	c, _ := start(t)
+	var wg sync.WaitGroup
+	for l1 := 0; l1 < 20; l1++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Call("fake/state", struct{}{}, nil)
+		}()
+	}
+	c.Close()
+	wg.Wait()
+	<-c.done
+}
+
+func TestStartingDoesNotBlock(t *testing.T) {
+	os.Setenv(fakeDelayEnv, "500ms")
+	defer os.Unsetenv(fakeDelayEnv)
+	file := filepath.Join(t.TempDir(), "main.go")
+
+	started := make(chan *Client)
+	go func() {
+		c, err := ForFile(file)
+		if err != nil {
+			t.Error(err)
+		}
+		started <- c
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	begin := time.Now()
+	CloseFile(file)
+	Shutdown()
+	if d := time.Since(begin); d > 100*time.Millisecond {
+		t.Errorf("CloseFile and Shutdown took %v while a server was starting", d)
+	}
+	// The server is shut down once it has started.
+	if c := <-started; c != nil {
+		select {
+		case <-c.done:
+		case <-time.After(5 * time.Second):
+			t.Error("server started during Shutdown was never closed")
+		}
+	}
+}
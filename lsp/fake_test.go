@@ -0,0 +1,186 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// The tests run their own binary as the language server: with fakeEnv set,
+// TestMain serves the protocol on stdin and stdout instead of running the
+// tests.
+const fakeEnv = "LSP_FAKE_SERVER"
+
+// fakeDelayEnv, if set, is how long the fake server takes to answer
+// initialize.
+const fakeDelayEnv = "LSP_FAKE_DELAY"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeEnv) != "" {
+		newFake(os.Stdin, os.Stdout).serve()
+		os.Exit(0)
+	}
+	os.Setenv(fakeEnv, "1")
+	Command = []string{os.Args[0]}
+	os.Exit(m.Run())
+}
+
+type fakeDoc struct {
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+// fakeState is what the fake server answers "fake/state" with, so tests
+// can see what it was told.
+type fakeState struct {
+	Methods     []string            `json:"methods"`
+	Docs        map[string]*fakeDoc `json:"docs"`
+	ConfigReply json.RawMessage     `json:"configReply"`
+}
+
+type fake struct {
+	r *bufio.Reader
+	w io.Writer
+	fakeState
+}
+
+func newFake(r io.Reader, w io.Writer) *fake {
+	return &fake{
+		r:         bufio.NewReader(r),
+		w:         w,
+		fakeState: fakeState{Docs: map[string]*fakeDoc{}},
+	}
+}
+
+func (f *fake) send(msg *message) {
+	msg.JSONRPC = "2.0"
+	data, _ := json.Marshal(msg)
+	fmt.Fprintf(f.w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func (f *fake) reply(id *json.RawMessage, result interface{}) {
+	data, _ := json.Marshal(result)
+	f.send(&message{ID: id, Result: data})
+}
+
+func (f *fake) serve() {
+	for {
+		length := 0
+		for {
+			line, err := f.r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				break
+			}
+			if strings.HasPrefix(line, "Content-Length:") {
+				length, _ = strconv.Atoi(strings.TrimSpace(line[len("Content-Length:"):]))
+			}
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(f.r, data); err != nil {
+			return
+		}
+		msg := &message{}
+		json.Unmarshal(data, msg)
+		if msg.Method == "" {
+			// The answer to our workspace/configuration request.
+			f.ConfigReply = msg.Result
+			continue
+		}
+		f.Methods = append(f.Methods, msg.Method)
+		f.handle(msg)
+	}
+}
+
+func (f *fake) handle(msg *message) {
+	switch msg.Method {
+	case "initialize":
+		if d, err := time.ParseDuration(os.Getenv(fakeDelayEnv)); err == nil {
+			time.Sleep(d)
+		}
+		// Servers ask the client things too. Asking before answering means
+		// the client's reply arrives ahead of anything it sends after Start.
+		id := json.RawMessage(`"config"`)
+		params, _ := json.Marshal(map[string]interface{}{"items": []interface{}{map[string]string{"section": "gopls"}, map[string]string{"section": "go"}}})
+		f.send(&message{ID: &id, Method: "workspace/configuration", Params: params})
+		f.reply(msg.ID, map[string]interface{}{"capabilities": map[string]interface{}{}})
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		json.Unmarshal(msg.Params, &p)
+		f.Docs[p.TextDocument.URI] = &fakeDoc{p.TextDocument.Version, p.TextDocument.Text}
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		json.Unmarshal(msg.Params, &p)
+		if doc := f.Docs[p.TextDocument.URI]; doc != nil {
+			doc.Version = p.TextDocument.Version
+			doc.Text = p.ContentChanges[len(p.ContentChanges)-1].Text
+		}
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		json.Unmarshal(msg.Params, &p)
+		delete(f.Docs, p.TextDocument.URI)
+	case "textDocument/completion":
+		// Completes the word before the position with "Completed", giving
+		// the document version as the detail. Words starting "list" get a
+		// bare array rather than a CompletionList.
+		word, doc := f.before(msg.Params)
+		if i := strings.LastIndexAny(word, " \t.("); i >= 0 {
+			word = word[i+1:]
+		}
+		items := []CompletionItem{{Label: word + "Completed", Kind: 3, Detail: "v" + strconv.Itoa(doc.Version)}}
+		if strings.HasPrefix(word, "list") {
+			f.reply(msg.ID, items)
+			return
+		}
+		f.reply(msg.ID, CompletionList{Items: items})
+	case "textDocument/signatureHelp":
+		// The signature is the line up to the position.
+		line, _ := f.before(msg.Params)
+		f.reply(msg.ID, SignatureHelp{Signatures: []SignatureInformation{{Label: line}}})
+	case "textDocument/definition":
+		var p TextDocumentPositionParams
+		json.Unmarshal(msg.Params, &p)
+		r := Range{Start: Position{Line: 0, Character: 1}, End: Position{Line: 0, Character: 2}}
+		f.reply(msg.ID, []LocationLink{{TargetURI: p.TextDocument.URI, TargetRange: r, TargetSelectionRange: r}})
+	case "fake/state":
+		f.reply(msg.ID, f.fakeState)
+	case "shutdown":
+		f.reply(msg.ID, nil)
+	case "exit":
+		os.Exit(0)
+	default:
+		if msg.ID != nil {
+			f.send(&message{ID: msg.ID, Error: &ResponseError{Code: -32601, Message: "unknown method " + msg.Method}})
+		}
+	}
+}
+
+// before returns the text of the line up to the position in params, which
+// counts UTF-16 code units as LSP does.
+func (f *fake) before(params json.RawMessage) (string, *fakeDoc) {
+	var p TextDocumentPositionParams
+	json.Unmarshal(params, &p)
+	doc := f.Docs[p.TextDocument.URI]
+	if doc == nil {
+		return "", &fakeDoc{}
+	}
+	lines := strings.Split(doc.Text, "\n")
+	if p.Position.Line >= len(lines) {
+		return "", doc
+	}
+	units := utf16.Encode([]rune(lines[p.Position.Line]))
+	if p.Position.Character < len(units) {
+		units = units[:p.Position.Character]
+	}
+	return string(utf16.Decode(units)), doc
+}
@@ -0,0 +1,42 @@
+package lsp
+
+import "unicode/utf8"
+
+// LSP positions count characters in UTF-16 code units; the editor counts
+// runes. These convert between the two over a snapshot of a buffer's text.
+
+func units(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
+
+// PositionOf returns the position of rune offset pos in text.
+func PositionOf(text []rune, pos int) Position {
+	p := Position{}
+	for l1 := 0; l1 < pos && l1 < len(text); l1++ {
+		if text[l1] == '\n' {
+			p.Line++
+			p.Character = 0
+			continue
+		}
+		p.Character += units(text[l1])
+	}
+	return p
+}
+
+// OffsetOf returns the rune offset of p in text, clamped to the end of its
+// line.
+func OffsetOf(text []rune, p Position) int {
+	l1 := 0
+	for line := 0; line < p.Line && l1 < len(text); l1++ {
+		if text[l1] == '\n' {
+			line++
+		}
+	}
+	for char := 0; char < p.Character && l1 < len(text) && text[l1] != '\n'; l1++ {
+		char += units(text[l1])
+	}
+	return l1
+}
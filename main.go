@@ -5,27 +5,38 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/andyleap/editor/buffer"
 	"github.com/andyleap/editor/core"
+	"github.com/andyleap/editor/diag"
 	"github.com/andyleap/editor/dialogs"
+	"github.com/andyleap/editor/diff"
 	"github.com/andyleap/editor/find"
 	"github.com/andyleap/editor/golight"
 	"github.com/andyleap/editor/gosense"
+	"github.com/andyleap/editor/grep"
+	"github.com/andyleap/editor/lsp"
 	"github.com/andyleap/editor/menu"
+	"github.com/andyleap/editor/nav"
 	"github.com/andyleap/editor/shortcuts"
+	"github.com/andyleap/editor/swap"
+	"github.com/andyleap/editor/tabs"
 
 	"github.com/andyleap/termbox-go"
 	"github.com/jessevdk/go-flags"
 )
 
 type CurPos struct {
-	b *buffer.Buffer
+	tm *tabs.Manager
 }
 
 func (c CurPos) Title() string {
-	return c.b.Filename + " L" + strconv.Itoa(c.b.CurY+1) + ":" + strconv.Itoa(c.b.CurX+1)
+	b := c.tm.Buf()
+	return b.Filename + " L" + strconv.Itoa(b.CurY+1) + ":" + strconv.Itoa(b.CurX+1)
 }
 
 func (c CurPos) Handle() bool {
@@ -37,11 +48,11 @@ func (c CurPos) SubMenu() []menu.MenuItem {
 }
 
 type Unsaved struct {
-	b *buffer.Buffer
+	tm *tabs.Manager
 }
 
 func (u Unsaved) Title() string {
-	if u.b.Dirty {
+	if u.tm.Buf().Dirty {
 		return "Unsaved"
 	}
 	return ""
@@ -55,8 +66,21 @@ func (u Unsaved) SubMenu() []menu.MenuItem {
 	return nil
 }
 
+// viewOptions are the display settings shared by every open buffer.
+type viewOptions struct {
+	Gutter          bool
+	RelativeNumbers bool
+	Wrap            bool
+}
+
+func (v viewOptions) apply(b *buffer.Buffer) {
+	b.Gutter, b.RelativeNumbers = v.Gutter, v.RelativeNumbers
+	b.Wrap, b.WrapIndent = v.Wrap, v.Wrap
+}
+
 var Options struct {
-	Log bool `long:"log"`
+	Log    bool `long:"log"`
+	Backup bool `long:"backup" description:"keep the previous version of saved files with a ~ suffix"`
 }
 
 func main() {
@@ -76,114 +100,581 @@ func main() {
 		Log: logger,
 	}
 
-	b := buffer.New(nil)
-
-	b.AddStyler(golight.New(b))
-
 	m := &menu.MenuBar{}
-	finder := &find.FindPanel{Buf: b}
+	finder := &find.FindPanel{}
 	fp := &core.Enableable{UI: finder}
 
-	gs := gosense.New(b)
+	newPane := func(b *buffer.Buffer) core.UI {
+		s := &core.Stack{}
+		s.Add(b)
+		s.Add(fp)
+		gs := gosense.New(b)
+		gs.Post = e.Post
+		s.Add(gs)
+		return buffer.NewView(b, s)
+	}
+
+	var GotoDefinition func(b *buffer.Buffer)
+	var RecoverFile func(b *buffer.Buffer, next func()) bool
+	view := viewOptions{}
 
-	s := &core.Stack{}
-	s.Add(b)
-	s.Add(fp)
-	s.Add(gs)
+	tm := &tabs.Manager{}
+	tm.NewTab = func(b *buffer.Buffer) *tabs.Tab {
+		b.AddStyler(golight.New(b))
+		b.AddStyler(find.NewHighlighter(finder, b, func() bool { return fp.Enabled }))
 
-	funcAssist := gosense.NewFuncAssist(b)
+		funcAssist := gosense.NewFuncAssist(b)
+		funcAssist.Post = e.Post
+
+		b.OnRightClick = func() { GotoDefinition(b) }
+		b.OnCtrlClick = func() { GotoDefinition(b) }
+
+		marks := diag.New(b)
+		marks.Post = e.Post
+		b.AddStyler(marks)
+		b.AddMarker(marks)
+		view.apply(b)
+		b.Backup = Options.Backup
+		b.OnSave = func() {
+			marks.Check()
+			swap.Remove(b.Filename)
+		}
+
+		return &tabs.Tab{
+			Buf:  b,
+			Main: newPane(b),
+			Bar:  &diag.Status{Marks: marks, Bar: funcAssist},
+		}
+	}
+	tm.OnSwitch = func(t *tabs.Tab) {
+		finder.Buf = t.Buf
+	}
 
 	m.Contents = &core.StatusBar{
-		Main: s,
-		Bar: funcAssist,
+		Main: tm,
+		Bar:  tm.StatusBar(),
 	}
 
-	if len(args) >= 1 {
-		b.LoadFile(args[0])
+	for _, arg := range args {
+		b := buffer.New(nil)
+		b.LoadFile(arg)
+		tm.Open(b)
+	}
+	if len(tm.Tabs) == 0 {
+		tm.Open(buffer.New(nil))
 	}
 
 	Fmt := func() {
+		b := tm.Buf()
+		rev := b.Rev()
 		cmd := exec.Command("gofmt")
-		stdin, _ := cmd.StdinPipe()
+		cmd.Stdin = strings.NewReader(b.Text())
 		go func() {
-			b.GB.WriteTo(stdin)
-			stdin.Close()
+			out, err := cmd.Output()
+			if err != nil {
+				return
+			}
+			e.Post(func() {
+				if b.Rev() == rev {
+					b.Update([]rune(string(out)))
+				}
+			})
 		}()
-		out, err := cmd.Output()
-		if err == nil {
-			b.Update([]rune(string(out)))
-		}
+	}
+
+	SaveFailed := func(err error) {
+		ed := dialogs.NewErrorDialog("Save failed", err)
+		ed.Close = func() { e.Remove(ed) }
+		e.Add(ed)
 	}
 
 	SaveAs := func(then func()) {
+		b := tm.Buf()
 		curDir, _ := os.Getwd()
 		sd := dialogs.NewSaveDialog(curDir)
 		sd.Save = func(fileName string) {
-			b.SaveFileAs(fileName)
 			e.Remove(sd)
+			if err := b.SaveFileAs(fileName); err != nil {
+				SaveFailed(err)
+				return
+			}
 			then()
 		}
 		e.Add(sd)
 	}
 
+	OpenFile := func(fileName string) {
+		if i := tm.Find(fileName); i >= 0 {
+			tm.Select(i)
+			return
+		}
+		b := tm.Buf()
+		if b.Filename == "" && !b.Dirty && b.GB.Len() == 0 {
+			b.LoadFile(fileName)
+			tm.Select(tm.Current)
+		} else {
+			b = buffer.New(nil)
+			b.LoadFile(fileName)
+			tm.Open(b)
+		}
+		RecoverFile(b, func() {})
+	}
+
 	Open := func() {
 		curDir, _ := os.Getwd()
 		od := dialogs.NewOpenDialog(curDir)
 		od.Load = func(fileName string) {
-			b.LoadFile(fileName)
+			OpenFile(fileName)
 			e.Remove(od)
 		}
 		e.Add(od)
 	}
 
+	ShowDiff := func(b *buffer.Buffer) {
+		onDisk, err := b.DiskText()
+		if err != nil {
+			ed := dialogs.NewErrorDialog("Diff failed", err)
+			ed.Close = func() { e.Remove(ed) }
+			e.Add(ed)
+			return
+		}
+		name := filepath.Base(b.Filename)
+		text := diff.Unified(name+" (on disk)", name+" (editor)", onDisk, b.Text())
+		if text == "" {
+			text = "No differences.\n"
+		}
+		tm.Open(buffer.New([]rune(text)))
+	}
+
 	Save := func(then func()) {
-		if b.SaveFile() != nil {
-			SaveAs(then)
+		b := tm.Buf()
+		var save func(force bool)
+		save = func(force bool) {
+			var err error
+			if force {
+				err = b.ForceSaveFile()
+			} else {
+				err = b.SaveFile()
+			}
+			switch err {
+			case nil:
+				then()
+			case buffer.ErrNoFilename:
+				SaveAs(then)
+			case buffer.ErrChanged:
+				d := &dialogs.Dialog{
+					Message: filepath.Base(b.Filename) + " has changed on disk since it was opened. Overwrite it?",
+				}
+				d.Options = []dialogs.Option{
+					{"Overwrite", func() { e.Remove(d); save(true) }},
+					{"Show diff", func() { e.Remove(d); ShowDiff(b) }},
+					{"Cancel", func() { e.Remove(d) }},
+				}
+				e.Add(d)
+			default:
+				SaveFailed(err)
+			}
+		}
+		if b.Lossy == "" {
+			save(false)
+			return
+		}
+		d := &dialogs.Dialog{
+			Message: filepath.Base(b.Filename) + " " + b.Lossy + ", so saving will alter it. Save anyway?",
+		}
+		d.Options = []dialogs.Option{
+			{"Save", func() { e.Remove(d); save(false) }},
+			{"Cancel", func() { e.Remove(d) }},
+		}
+		e.Add(d)
+	}
+
+	// Journal writes the buffers changed since it last ran to their swap
+	// files, and removes the swap files of those since saved or closed.
+	// Buffers with no file are journaled under a key of their own.
+	type journal struct {
+		filename string
+		key      string
+		rev      int
+	}
+	journaled := map[*buffer.Buffer]journal{}
+	unjournal := func(j journal) {
+		if j.filename == "" {
+			swap.RemoveUntitled(j.key)
+			return
+		}
+		swap.Remove(j.filename)
+	}
+	Journal := func() {
+		open := map[*buffer.Buffer]bool{}
+		for _, t := range tm.Tabs {
+			b := t.Buf
+			open[b] = true
+			j, ok := journaled[b]
+			if !b.Dirty {
+				if ok {
+					unjournal(j)
+					delete(journaled, b)
+				}
+				continue
+			}
+			if ok && j.filename == b.Filename && j.rev == b.Rev() {
+				continue
+			}
+			if ok && j.filename != b.Filename {
+				unjournal(j)
+				j = journal{}
+			}
+			var err error
+			if b.Filename == "" {
+				if j.key == "" {
+					j.key = swap.NewKey()
+				}
+				err = swap.WriteUntitled(j.key, b.Text())
+			} else {
+				err = swap.Write(b.Filename, b.Text())
+			}
+			if err == nil {
+				journaled[b] = journal{b.Filename, j.key, b.Rev()}
+			}
+		}
+		for b, j := range journaled {
+			if !open[b] {
+				unjournal(j)
+				delete(journaled, b)
+			}
+		}
+	}
+	go func() {
+		for range time.Tick(5 * time.Second) {
+			e.Post(Journal)
+		}
+	}()
+
+	// RecoverUntitled offers, in turn, each of swaps, left behind by
+	// buffers that were never given a file, skipping those of buffers
+	// still open.
+	var RecoverUntitled func(swaps []*swap.Swap)
+	RecoverUntitled = func(swaps []*swap.Swap) {
+		live := map[string]bool{}
+		for _, j := range journaled {
+			live[j.key] = true
+		}
+		for len(swaps) > 0 && live[swaps[0].Key] {
+			swaps = swaps[1:]
+		}
+		if len(swaps) == 0 {
+			return
+		}
+		s, rest := swaps[0], swaps[1:]
+		d := &dialogs.Dialog{
+			Message: "An untitled buffer has unsaved changes from " + s.Time.Format("Jan 2 15:04") + ". Recover it?",
+		}
+		d.Options = []dialogs.Option{
+			{"Recover", func() {
+				e.Remove(d)
+				b := tm.Open(buffer.New([]rune(s.Text))).Buf
+				b.Dirty = true
+				// Rewritten on the next Journal, so it is marked as ours.
+				journaled[b] = journal{key: s.Key, rev: -1}
+				RecoverUntitled(rest)
+			}},
+			{"Discard", func() {
+				e.Remove(d)
+				swap.RemoveUntitled(s.Key)
+				RecoverUntitled(rest)
+			}},
+		}
+		e.Add(d)
+	}
+
+	// RecoverFile offers the changes to b's file found in its swap file,
+	// left behind by an editor that never saved them, then calls next. It
+	// reports whether there were any to offer.
+	RecoverFile = func(b *buffer.Buffer, next func()) bool {
+		if b.Filename == "" {
+			return false
+		}
+		s, ok := swap.Pending(b.Filename)
+		if !ok {
+			return false
+		}
+		d := &dialogs.Dialog{
+			Message: filepath.Base(b.Filename) + " has unsaved changes from " + s.Time.Format("Jan 2 15:04") + ". Recover them?",
+		}
+		d.Options = []dialogs.Option{
+			{"Recover", func() {
+				e.Remove(d)
+				b.Update([]rune(s.Text))
+				next()
+			}},
+			{"Show diff", func() {
+				e.Remove(d)
+				name := filepath.Base(b.Filename)
+				text := diff.Unified(name, name+" (recovered)", b.Text(), s.Text)
+				if text == "" {
+					text = "No differences.\n"
+				}
+				tm.Open(buffer.New([]rune(text)))
+				next()
+			}},
+			{"Discard", func() {
+				e.Remove(d)
+				swap.Remove(b.Filename)
+				next()
+			}},
+		}
+		e.Add(d)
+		return true
+	}
+
+	// Recover offers, in turn, the changes found in swap files for each
+	// tab from i on, then those of buffers that had no file.
+	var Recover func(i int)
+	Recover = func(i int) {
+		for ; i < len(tm.Tabs); i++ {
+			next := i + 1
+			if RecoverFile(tm.Tabs[i].Buf, func() { Recover(next) }) {
+				return
+			}
+		}
+		RecoverUntitled(swap.Untitled())
+	}
+
+	// CheckDisk asks what to do about the first open file found to have
+	// been changed by something else, one file at a time.
+	prompting := false
+	CheckDisk := func() {
+		if prompting {
+			return
+		}
+		for _, t := range tm.Tabs {
+			b := t.Buf
+			if !b.PollDisk() {
+				continue
+			}
+			prompting = true
+			d := &dialogs.Dialog{
+				Message: filepath.Base(b.Filename) + " has been changed on disk.",
+			}
+			done := func() {
+				e.Remove(d)
+				prompting = false
+			}
+			d.Options = []dialogs.Option{
+				{"Reload", func() {
+					done()
+					if err := b.Reload(); err != nil {
+						ed := dialogs.NewErrorDialog("Reload failed", err)
+						ed.Close = func() { e.Remove(ed) }
+						e.Add(ed)
+					}
+				}},
+				{"Keep mine", done},
+				{"Show diff", func() { done(); ShowDiff(b) }},
+			}
+			e.Add(d)
+			return
+		}
+	}
+	go func() {
+		for range time.Tick(2 * time.Second) {
+			e.Post(CheckDisk)
+		}
+	}()
+
+	// closeTab closes tab i without asking, telling the language server
+	// its file is no longer open.
+	closeTab := func(i int) {
+		if i >= 0 && i < len(tm.Tabs) {
+			lsp.CloseFile(tm.Tabs[i].Buf.Filename)
+		}
+		tm.Close(i)
+	}
+
+	Close := func(i int) {
+		tm.Select(i)
+		if tm.Buf().Dirty {
+			d := &dialogs.Dialog{
+				Message: "You have unsaved changes, do you wish save them?",
+			}
+			d.Options = []dialogs.Option{
+				{"Save", func() { Save(func() { closeTab(tm.Current); e.Remove(d) }) }},
+				{"Discard", func() { closeTab(tm.Current); e.Remove(d) }},
+				{"Cancel", func() { e.Remove(d) }},
+			}
+			e.Add(d)
+		} else {
+			closeTab(i)
+		}
+	}
+	tm.OnClose = Close
+
+	history := &nav.History{}
+
+	here := func() nav.Location {
+		b := tm.Buf()
+		return nav.Location{Filename: b.Filename, Pos: b.Pos()}
+	}
+
+	JumpTo := func(loc nav.Location) {
+		OpenFile(loc.Filename)
+		b := tm.Buf()
+		if loc.Pos > b.GB.Len() {
+			loc.Pos = b.GB.Len()
+		}
+		b.Sel = -1
+		b.SetPos(loc.Pos)
+	}
+
+	GotoDefinition = func(b *buffer.Buffer) {
+		// The language server only knows Go.
+		if !b.IsGo() {
+			return
+		}
+		filename, rev, text, pos := b.Filename, b.Rev(), b.Text(), b.Pos()
+		go func() {
+			c, err := lsp.ForFile(filename)
+			if err != nil {
+				return
+			}
+			if err := c.Sync(filename, rev, text); err != nil {
+				return
+			}
+			locs, err := c.Definition(filename, lsp.PositionOf([]rune(text), pos))
+			if err != nil || len(locs) == 0 {
+				return
+			}
+			e.Post(func() {
+				if b.Rev() != rev || b.Pos() != pos || tm.Buf() != b {
+					return
+				}
+				history.Push(here())
+				target := lsp.Filename(locs[0].URI)
+				OpenFile(target)
+				tb := tm.Buf()
+				tb.Sel = -1
+				tb.SetPos(lsp.OffsetOf([]rune(tb.Text()), locs[0].Range.Start))
+			})
+		}()
+	}
+
+	Back := func() {
+		if loc, ok := history.Back(here()); ok {
+			JumpTo(loc)
+		}
+	}
+
+	Forward := func() {
+		if loc, ok := history.Forward(here()); ok {
+			JumpTo(loc)
+		}
+	}
+
+	curDir, _ := os.Getwd()
+	gp := grep.NewPanel(curDir)
+	gp.Post = e.Post
+	gp.Close = func() {
+		e.Remove(gp)
+	}
+	gp.Open = func(res grep.Result) {
+		e.Remove(gp)
+		history.Push(here())
+		OpenFile(res.File)
+		b := tm.Buf()
+		b.Sel = -1
+		// The file may have changed since it was searched, so keep to the
+		// line the match was on.
+		pos := b.LineOffset(res.Line) + res.Col
+		if end := b.LineEnd(res.Line); pos > end {
+			pos = end
+		}
+		b.SetPos(pos)
+	}
+
+	FindInFiles := func() {
+		e.Remove(gp)
+		e.Add(gp)
+	}
+
+	SplitPane := func(vertical bool) {
+		t := tm.Active()
+		if split, ok := t.Main.(*core.Split); ok {
+			split.SplitFocused(newPane(t.Buf), vertical)
+			return
+		}
+		var split *core.Split
+		if vertical {
+			split = core.NewVSplit(t.Main, newPane(t.Buf))
+		} else {
+			split = core.NewHSplit(t.Main, newPane(t.Buf))
+		}
+		split.FocusOn(1)
+		t.Main = split
+	}
+
+	ClosePane := func() {
+		t := tm.Active()
+		if split, ok := t.Main.(*core.Split); ok {
+			t.Main = split.CloseFocused()
+		}
+	}
+
+	SetView := func(v viewOptions) {
+		view = v
+		for _, t := range tm.Tabs {
+			view.apply(t.Buf)
+		}
+	}
+
+	NextPane := func() {
+		if split, ok := tm.Active().Main.(*core.Split); ok {
+			split.FocusNext()
 		}
 	}
 
 	Exit := func() {
+		for _, j := range journaled {
+			unjournal(j)
+		}
+		lsp.Shutdown()
 		termbox.Close()
 		os.Exit(0)
 	}
 
+	firstDirty := func() int {
+		for i, t := range tm.Tabs {
+			if t.Buf.Dirty {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var SaveAll func(then func())
+	SaveAll = func(then func()) {
+		i := firstDirty()
+		if i < 0 {
+			then()
+			return
+		}
+		tm.Select(i)
+		Save(func() { SaveAll(then) })
+	}
+
 	m.Items = []menu.MenuItem{
 		menu.Menu{
 			"File",
 			[]menu.MenuItem{
 				menu.MenuAction{
 					"New", func() bool {
-						if b.Dirty {
-							d := &dialogs.Dialog{
-								Message: "You have unsaved changes, do you wish save them?",
-							}
-							d.Options = []dialogs.Option{
-								{"Save", func() { Save(func() { b.Load(nil); e.Remove(d) }) }},
-								{"Discard", func() { b.Load(nil); e.Remove(d) }},
-								{"Cancel", func() { e.Remove(d) }},
-							}
-							e.Add(d)
-						} else {
-							b.Load(nil)
-						}
+						tm.Open(buffer.New(nil))
 						return true
 					},
 				},
 				menu.MenuAction{
 					"Open", func() bool {
-						if b.Dirty {
-							d := &dialogs.Dialog{
-								Message: "You have unsaved changes, do you wish to save or discard them?",
-							}
-							d.Options = []dialogs.Option{
-								{"Save", func() { Save(func() { Open(); e.Remove(d) }) }},
-								{"Discard", func() { Open(); e.Remove(d) }},
-								{"Cancel", func() { e.Remove(d) }},
-							}
-							e.Add(d)
-						} else {
-							Open()
-						}
+						Open()
 						return true
 					},
 				},
@@ -199,15 +690,27 @@ func main() {
 						return true
 					},
 				},
+				menu.MenuAction{
+					"Close", func() bool {
+						Close(tm.Current)
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Recover", func() bool {
+						Recover(0)
+						return true
+					},
+				},
 				menu.Separator{},
 				menu.MenuAction{
 					"Exit", func() bool {
-						if b.Dirty {
+						if firstDirty() >= 0 {
 							d := &dialogs.Dialog{
 								Message: "You have unsaved changes, do you still wish save them before exiting?",
 							}
 							d.Options = []dialogs.Option{
-								{"Save", func() { Save(func() { Exit(); e.Remove(d) }) }},
+								{"Save", func() { e.Remove(d); SaveAll(Exit) }},
 								{"Discard", func() { Exit(); e.Remove(d) }},
 								{"Cancel", func() { e.Remove(d) }},
 							}
@@ -220,6 +723,41 @@ func main() {
 				},
 			},
 		},
+		menu.Menu{
+			"Edit",
+			[]menu.MenuItem{
+				menu.MenuAction{
+					"Undo", func() bool {
+						tm.Buf().Undo()
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Redo", func() bool {
+						tm.Buf().Redo()
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Cursor Above", func() bool {
+						tm.Buf().AddCursor(-1)
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Cursor Below", func() bool {
+						tm.Buf().AddCursor(1)
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Next Occurrence", func() bool {
+						tm.Buf().AddNextOccurrence()
+						return true
+					},
+				},
+			},
+		},
 		menu.Menu{
 			"Find",
 			[]menu.MenuItem{
@@ -229,10 +767,121 @@ func main() {
 						return true
 					},
 				},
+				menu.MenuAction{
+					"Find in Files", func() bool {
+						FindInFiles()
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Replace Next", func() bool {
+						finder.ReplaceNext()
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Replace All", func() bool {
+						finder.ReplaceAll()
+						return true
+					},
+				},
 			},
 		},
-		CurPos{b},
-		Unsaved{b},
+		menu.Menu{
+			"Go",
+			[]menu.MenuItem{
+				menu.MenuAction{
+					"Definition", func() bool {
+						GotoDefinition(tm.Buf())
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Back", func() bool {
+						Back()
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Forward", func() bool {
+						Forward()
+						return true
+					},
+				},
+			},
+		},
+		menu.Menu{
+			"View",
+			[]menu.MenuItem{
+				menu.MenuAction{
+					"Split Side", func() bool {
+						SplitPane(false)
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Split Below", func() bool {
+						SplitPane(true)
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Line Numbers", func() bool {
+						v := view
+						v.Gutter = !v.Gutter
+						SetView(v)
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Relative Numbers", func() bool {
+						v := view
+						v.Gutter, v.RelativeNumbers = true, !v.RelativeNumbers
+						SetView(v)
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Word Wrap", func() bool {
+						v := view
+						v.Wrap = !v.Wrap
+						SetView(v)
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Next Pane", func() bool {
+						NextPane()
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Close Pane", func() bool {
+						ClosePane()
+						return true
+					},
+				},
+			},
+		},
+		menu.Menu{
+			"Tabs",
+			[]menu.MenuItem{
+				menu.MenuAction{
+					"Next", func() bool {
+						tm.Next()
+						return true
+					},
+				},
+				menu.MenuAction{
+					"Previous", func() bool {
+						tm.Prev()
+						return true
+					},
+				},
+			},
+		},
+		CurPos{tm},
+		Unsaved{tm},
 	}
 
 	e.Add(m)
@@ -244,8 +893,14 @@ func main() {
 	scs.Add(termbox.KeyCtrlF, func() {
 		Fmt()
 	})
+	scs.Add(termbox.KeyCtrlZ, func() {
+		tm.Buf().Undo()
+	})
+	scs.Add(termbox.KeyCtrlY, func() {
+		tm.Buf().Redo()
+	})
 	scs.Add(termbox.KeyCtrlX, func() {
-		if !b.Dirty {
+		if firstDirty() < 0 {
 			Exit()
 		}
 	})
@@ -255,14 +910,37 @@ func main() {
 			finder.Focus()
 		}
 	})
+	scs.Add(termbox.KeyCtrlG, func() {
+		FindInFiles()
+	})
 	scs.AddMod(termbox.KeyArrowDown, termbox.ModAlt, func() {
 		finder.Search(false)
 	})
 	scs.AddMod(termbox.KeyArrowUp, termbox.ModAlt, func() {
 		finder.Search(true)
 	})
+	scs.Add(termbox.KeyF12, func() {
+		GotoDefinition(tm.Buf())
+	})
+	scs.AddMod(termbox.KeyArrowLeft, termbox.ModAlt, func() {
+		Back()
+	})
+	scs.AddMod(termbox.KeyArrowRight, termbox.ModAlt, func() {
+		Forward()
+	})
+	scs.Add(termbox.KeyF6, func() {
+		NextPane()
+	})
+	scs.AddMod(termbox.KeyPgdn, termbox.ModCtrl, func() {
+		tm.Next()
+	})
+	scs.AddMod(termbox.KeyPgup, termbox.ModCtrl, func() {
+		tm.Prev()
+	})
 
 	e.Add(scs)
 
+	Recover(0)
+
 	e.Run()
 }
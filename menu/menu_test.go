@@ -0,0 +1,53 @@
+package menu
+
+import (
+	"testing"
+
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/editor/core/screentest"
+	"github.com/andyleap/termbox-go"
+)
+
+type blank struct{}
+
+func (blank) Render(r core.Rect)                         {}
+func (blank) Handle(r core.Rect, evt termbox.Event) bool { return false }
+
+func newBar() *MenuBar {
+	nop := func() bool { return true }
+	return &MenuBar{
+		Items: []MenuItem{
+			Menu{"File", []MenuItem{
+				MenuAction{"Open", nop},
+				MenuAction{"Save", nop},
+				Separator{},
+				Menu{"Recent", []MenuItem{
+					MenuAction{"main.go", nop},
+				}},
+			}},
+			Menu{"Edit", []MenuItem{
+				MenuAction{"Undo", nop},
+			}},
+		},
+		Contents: blank{},
+	}
+}
+
+func click(m *MenuBar, x, y int) {
+	m.Handle(core.Rect{W: 40, H: 8}, termbox.Event{Type: termbox.EventMouse, Key: termbox.MouseLeft, MouseX: x, MouseY: y})
+}
+
+func TestMenuBar(t *testing.T) {
+	m := newBar()
+	screentest.Golden(t, "closed", screentest.Render(m, 40, 8).String())
+
+	click(m, 2, 0)
+	screentest.Golden(t, "file", screentest.Render(m, 40, 8).String())
+
+	click(m, 3, 4)
+	s := screentest.Render(m, 40, 8)
+	screentest.Golden(t, "recent", s.String()+"\n"+screentest.Highlights(s))
+
+	click(m, 30, 6)
+	screentest.Golden(t, "closed", screentest.Render(m, 40, 8).String())
+}
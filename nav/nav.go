@@ -0,0 +1,60 @@
+// Package nav keeps the jump history used to return from go-to-definition
+// and other jumps.
+package nav
+
+const maxHistory = 100
+
+// Location is a place in a file, as a rune offset into its buffer.
+type Location struct {
+	Filename string
+	Pos      int
+}
+
+// History is a browser-style back/forward stack of locations.
+type History struct {
+	back    []Location
+	forward []Location
+}
+
+// Push records from, the place being jumped away from, and forgets
+// anything that could have been returned to with Forward.
+func (h *History) Push(from Location) {
+	if from.Filename == "" {
+		return
+	}
+	if n := len(h.back); n > 0 && h.back[n-1] == from {
+		return
+	}
+	h.back = append(h.back, from)
+	if len(h.back) > maxHistory {
+		h.back = h.back[len(h.back)-maxHistory:]
+	}
+	h.forward = h.forward[:0]
+}
+
+// Back returns the last place jumped away from, remembering cur so Forward
+// can return to it.
+func (h *History) Back(cur Location) (Location, bool) {
+	if len(h.back) == 0 {
+		return Location{}, false
+	}
+	to := h.back[len(h.back)-1]
+	h.back = h.back[:len(h.back)-1]
+	if cur.Filename != "" {
+		h.forward = append(h.forward, cur)
+	}
+	return to, true
+}
+
+// Forward undoes a Back.
+func (h *History) Forward(cur Location) (Location, bool) {
+	if len(h.forward) == 0 {
+		return Location{}, false
+	}
+	to := h.forward[len(h.forward)-1]
+	h.forward = h.forward[:len(h.forward)-1]
+	if cur.Filename != "" {
+		h.back = append(h.back, cur)
+	}
+	return to, true
+}
@@ -0,0 +1,222 @@
+// Package swap journals unsaved buffers to swap files, so that their
+// changes can be recovered if the editor dies before they are saved.
+package swap
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// Swap is the journaled contents of a file, or of a buffer with no file,
+// which is known by a Key instead. PID is the process that wrote it.
+type Swap struct {
+	Filename string
+	Key      string `json:",omitempty"`
+	PID      int    `json:",omitempty"`
+	Time     time.Time
+	Text     string `json:"-"`
+}
+
+// pid is written into swaps as their owner.
+var pid = os.Getpid()
+
+// Live reports whether the editor that wrote s is still running, in which
+// case the swap is still its own to update and remove.
+func (s *Swap) Live() bool {
+	if s.PID <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(s.PID)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Dir returns the directory swap files are kept in, creating it if need
+// be.
+func Dir() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cache, "editor", "swap")
+	return dir, os.MkdirAll(dir, 0700)
+}
+
+// Path returns the swap file for filename, named for a hash of its
+// absolute path.
+func Path(filename string) (string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16])+".swp"), nil
+}
+
+// untitledPath returns the swap file for the buffer with no file known by
+// key.
+func untitledPath(key string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "untitled-"+key+".swp"), nil
+}
+
+// NewKey returns a key to journal a buffer with no file under.
+func NewKey() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Write journals text as the unsaved contents of filename. A swap file is
+// a line of JSON describing it followed by the text.
+func Write(filename, text string) error {
+	path, err := Path(filename)
+	if err != nil {
+		return err
+	}
+	abs, _ := filepath.Abs(filename)
+	return write(path, Swap{Filename: abs, PID: pid, Time: time.Now()}, text)
+}
+
+// WriteUntitled journals text as the contents of the buffer with no file
+// known by key.
+func WriteUntitled(key, text string) error {
+	path, err := untitledPath(key)
+	if err != nil {
+		return err
+	}
+	return write(path, Swap{Key: key, PID: pid, Time: time.Now()}, text)
+}
+
+func write(path string, s Swap, text string) error {
+	header, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".swp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	w.Write(header)
+	w.WriteByte('\n')
+	w.WriteString(text)
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Read returns the swap for filename.
+func Read(filename string) (*Swap, error) {
+	path, err := Path(filename)
+	if err != nil {
+		return nil, err
+	}
+	return read(path)
+}
+
+func read(path string) (*Swap, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	nl := bytes.IndexByte(data, '\n')
+	if nl < 0 {
+		nl = len(data)
+	}
+	s := &Swap{}
+	if err := json.Unmarshal(data[:nl], s); err != nil {
+		return nil, err
+	}
+	if nl < len(data) {
+		s.Text = string(data[nl+1:])
+	}
+	return s, nil
+}
+
+// Remove deletes the swap for filename, if there is one.
+func Remove(filename string) error {
+	path, err := Path(filename)
+	if err != nil {
+		return err
+	}
+	return remove(path)
+}
+
+// RemoveUntitled deletes the swap for the buffer with no file known by
+// key, if there is one.
+func RemoveUntitled(key string) error {
+	path, err := untitledPath(key)
+	if err != nil {
+		return err
+	}
+	return remove(path)
+}
+
+func remove(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Pending returns the swap for filename if it holds changes made after
+// the file was last written, by an editor no longer running. Swaps older
+// than their files are out of date and are removed.
+func Pending(filename string) (*Swap, bool) {
+	s, err := Read(filename)
+	if err != nil || s.Live() {
+		return nil, false
+	}
+	if fi, err := os.Stat(filename); err == nil && !s.Time.After(fi.ModTime()) {
+		Remove(filename)
+		return nil, false
+	}
+	return s, true
+}
+
+// Untitled returns the swaps of buffers with no file, oldest first,
+// leaving out those of editors still running.
+func Untitled() []*Swap {
+	dir, err := Dir()
+	if err != nil {
+		return nil
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "untitled-*.swp"))
+	var swaps []*Swap
+	for _, path := range paths {
+		if s, err := read(path); err == nil && s.Key != "" && !s.Live() {
+			swaps = append(swaps, s)
+		}
+	}
+	sort.Slice(swaps, func(i, j int) bool { return swaps[i].Time.Before(swaps[j].Time) })
+	return swaps
+}
@@ -0,0 +1,88 @@
+package swap
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// asDeadEditor has swaps written as if by an editor that has since died.
+func asDeadEditor(t *testing.T) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	old := pid
+	pid = cmd.ProcessState.Pid()
+	t.Cleanup(func() { pid = old })
+}
+
+func TestWriteRead(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	asDeadEditor(t)
+	filename := filepath.Join(t.TempDir(), "missing.txt")
+	if err := Write(filename, "unsaved\ntext"); err != nil {
+		t.Fatal(err)
+	}
+	// The file was never written, so the swap is newer than it.
+	s, ok := Pending(filename)
+	if !ok || s.Filename != filename || s.Text != "unsaved\ntext" {
+		t.Fatalf("got %+v, %v, want the swap for %s", s, ok, filename)
+	}
+	if err := Remove(filename); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Pending(filename); ok {
+		t.Error("swap still pending after Remove")
+	}
+}
+
+func TestUntitled(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	asDeadEditor(t)
+	first, second := NewKey(), NewKey()
+	if first == second {
+		t.Fatalf("NewKey returned %s twice", first)
+	}
+	WriteUntitled(first, "first")
+	WriteUntitled(second, "second")
+	// Named files' swaps aren't listed.
+	Write(filepath.Join(t.TempDir(), "named.txt"), "named")
+	// Rewriting a swap replaces it, making it the newest.
+	WriteUntitled(first, "first again")
+
+	swaps := Untitled()
+	if len(swaps) != 2 {
+		t.Fatalf("got %d swaps, want 2", len(swaps))
+	}
+	if swaps[0].Key != second || swaps[0].Text != "second" || swaps[0].Filename != "" {
+		t.Errorf("first swap is %+v, want %s holding \"second\"", swaps[0], second)
+	}
+	if swaps[1].Key != first || swaps[1].Text != "first again" {
+		t.Errorf("second swap is %+v, want %s holding \"first again\"", swaps[1], first)
+	}
+
+	RemoveUntitled(second)
+	if swaps := Untitled(); len(swaps) != 1 || swaps[0].Key != first {
+		t.Errorf("after RemoveUntitled got %+v, want only %s", swaps, first)
+	}
+}
+
+func TestLiveSwapsAreLeftAlone(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	filename := filepath.Join(t.TempDir(), "open.txt")
+	Write(filename, "still being edited")
+	WriteUntitled(NewKey(), "also being edited")
+
+	if _, ok := Pending(filename); ok {
+		t.Error("a running editor's swap is pending")
+	}
+	if s, err := Read(filename); err != nil || !s.Live() {
+		t.Errorf("got %+v, %v, want the swap kept and live", s, err)
+	}
+	if swaps := Untitled(); len(swaps) != 0 {
+		t.Errorf("got %d untitled swaps from a running editor, want none", len(swaps))
+	}
+}
@@ -0,0 +1,194 @@
+package tabs
+
+import (
+	"path/filepath"
+
+	"github.com/andyleap/editor/buffer"
+	"github.com/andyleap/editor/core"
+	"github.com/andyleap/termbox-go"
+)
+
+// Tab is one open buffer along with the UI layered over it and the
+// contents of the status bar while it is active.
+type Tab struct {
+	Buf  *buffer.Buffer
+	Main core.UI
+	Bar  core.UI
+}
+
+func (t *Tab) Title() string {
+	title := "untitled"
+	if t.Buf.Filename != "" {
+		title = filepath.Base(t.Buf.Filename)
+	}
+	if t.Buf.Dirty {
+		title += "*"
+	}
+	return title
+}
+
+// Manager holds every open buffer and draws a strip of tabs above the
+// active one.
+type Manager struct {
+	Tabs    []*Tab
+	Current int
+
+	// NewTab builds the tab, stylers and popups for a freshly opened
+	// buffer.
+	NewTab func(b *buffer.Buffer) *Tab
+	// OnSwitch is called whenever a different tab becomes active.
+	OnSwitch func(t *Tab)
+	// OnClose is called when the close glyph of a tab is clicked.
+	OnClose func(i int)
+}
+
+func (m *Manager) Active() *Tab {
+	if m.Current < 0 || m.Current >= len(m.Tabs) {
+		return nil
+	}
+	return m.Tabs[m.Current]
+}
+
+// Buf returns the active buffer.
+func (m *Manager) Buf() *buffer.Buffer {
+	if t := m.Active(); t != nil {
+		return t.Buf
+	}
+	return nil
+}
+
+// Open adds a tab for b and makes it active.
+func (m *Manager) Open(b *buffer.Buffer) *Tab {
+	t := m.NewTab(b)
+	m.Tabs = append(m.Tabs, t)
+	m.Select(len(m.Tabs) - 1)
+	return t
+}
+
+// Find returns the index of the tab editing filename, or -1.
+func (m *Manager) Find(filename string) int {
+	abs, _ := filepath.Abs(filename)
+	for i, t := range m.Tabs {
+		if t.Buf.Filename == "" {
+			continue
+		}
+		if p, _ := filepath.Abs(t.Buf.Filename); p == abs {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Manager) Select(i int) {
+	if i < 0 || i >= len(m.Tabs) {
+		return
+	}
+	m.Current = i
+	if m.OnSwitch != nil {
+		m.OnSwitch(m.Tabs[i])
+	}
+}
+
+func (m *Manager) Next() {
+	if len(m.Tabs) > 0 {
+		m.Select((m.Current + 1) % len(m.Tabs))
+	}
+}
+
+func (m *Manager) Prev() {
+	if len(m.Tabs) > 0 {
+		m.Select((m.Current + len(m.Tabs) - 1) % len(m.Tabs))
+	}
+}
+
+// Close removes tab i. Closing the last tab leaves a fresh empty buffer in
+// its place.
+func (m *Manager) Close(i int) {
+	if i < 0 || i >= len(m.Tabs) {
+		return
+	}
+	m.Tabs = append(m.Tabs[:i], m.Tabs[i+1:]...)
+	if len(m.Tabs) == 0 {
+		m.Open(buffer.New(nil))
+		return
+	}
+	if m.Current >= len(m.Tabs) || m.Current > i {
+		m.Current--
+	}
+	m.Select(m.Current)
+}
+
+func (m *Manager) tabArea(r core.Rect) []core.Rect {
+	areas := make([]core.Rect, len(m.Tabs))
+	xPos := r.X
+	for i, t := range m.Tabs {
+		w := len([]rune(t.Title())) + 4
+		areas[i] = core.Rect{X: xPos, Y: r.Y, W: w, H: 1}
+		xPos += w
+	}
+	return areas
+}
+
+func (m *Manager) Render(r core.Rect) {
+	for l1 := r.X; l1 < r.X+r.W; l1++ {
+		core.SetCell(l1, r.Y, ' ', termbox.ColorWhite, termbox.ColorBlack)
+	}
+	for i, a := range m.tabArea(r) {
+		fg, bg := termbox.ColorWhite, termbox.ColorBlack
+		if i == m.Current {
+			fg, bg = termbox.ColorBlack, termbox.ColorWhite
+		}
+		for l1 := a.X; l1 < a.X+a.W && l1 < r.X+r.W; l1++ {
+			core.SetCell(l1, a.Y, ' ', fg, bg)
+		}
+		core.RenderString(a.X+1, a.Y, m.Tabs[i].Title(), fg, bg)
+		core.SetCell(a.X+a.W-2, a.Y, '×', fg, bg)
+	}
+	if t := m.Active(); t != nil {
+		t.Main.Render(core.Rect{X: r.X, Y: r.Y + 1, W: r.W, H: r.H - 1})
+	}
+}
+
+func (m *Manager) Handle(r core.Rect, evt termbox.Event) bool {
+	if evt.Type == termbox.EventMouse && evt.MouseY == r.Y && evt.Key == termbox.MouseLeft {
+		for i, a := range m.tabArea(r) {
+			if !a.CheckEvent(evt) {
+				continue
+			}
+			if evt.MouseX == a.X+a.W-2 && m.OnClose != nil {
+				m.OnClose(i)
+			} else {
+				m.Select(i)
+			}
+			return true
+		}
+		return true
+	}
+	if t := m.Active(); t != nil {
+		return t.Main.Handle(core.Rect{X: r.X, Y: r.Y + 1, W: r.W, H: r.H - 1}, evt)
+	}
+	return false
+}
+
+// StatusBar returns a UI showing the status bar contents of whichever tab
+// is active.
+func (m *Manager) StatusBar() core.UI {
+	return statusBar{m}
+}
+
+type statusBar struct {
+	m *Manager
+}
+
+func (s statusBar) Render(r core.Rect) {
+	if t := s.m.Active(); t != nil && t.Bar != nil {
+		t.Bar.Render(r)
+	}
+}
+
+func (s statusBar) Handle(r core.Rect, evt termbox.Event) bool {
+	if t := s.m.Active(); t != nil && t.Bar != nil {
+		return t.Bar.Handle(r, evt)
+	}
+	return false
+}